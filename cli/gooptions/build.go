@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BuildConfig holds the go command settings used both to load packages and to
+// build the reflection programs, so both see the same files.
+type BuildConfig struct {
+	// GoCmd is the go command binary.
+	GoCmd string
+
+	// Tags are the build tags, as for go build -tags.
+	Tags string
+
+	// GoFlags are added to the GOFLAGS of the environment.
+	GoFlags string
+
+	// GOOS and GOARCH override the environment's when not empty. The
+	// reflection programs are run, so they must be executable on this machine.
+	GOOS   string
+	GOARCH string
+
+	// binDir is the bin directory of the selected toolchain, put first in the
+	// PATH of the go commands so the tools they run are the toolchain's too.
+	binDir string
+}
+
+// NewBuildConfig returns a BuildConfig for the go command goCmd, a name looked
+// up in PATH or a path. goCmd may also be a wrapper such as the ones installed
+// by golang.org/dl, which is resolved to the go binary of its GOROOT. The
+// environment of the process is left alone, the toolchain is only selected in
+// the environment of the go commands, see Env.
+func NewBuildConfig(goCmd, tags, goflags, goos, goarch string) (*BuildConfig, error) {
+	bc := &BuildConfig{
+		GoCmd:   "go",
+		Tags:    tags,
+		GoFlags: goflags,
+		GOOS:    goos,
+		GOARCH:  goarch,
+	}
+
+	if goCmd == "" || goCmd == "go" {
+		return bc, nil
+	}
+
+	output, err := exec.Command(goCmd, "env", "GOROOT").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find GOROOT of %v: %v", goCmd, err)
+	}
+	binDir := filepath.Join(strings.TrimSpace(string(output)), "bin")
+	bc.GoCmd = filepath.Join(binDir, "go")
+	bc.binDir = binDir
+
+	return bc, nil
+}
+
+// BuildFlags returns the flags to pass to go commands that build packages.
+func (bc *BuildConfig) BuildFlags() []string {
+	if bc.Tags == "" {
+		return nil
+	}
+	return []string{"-tags=" + bc.Tags}
+}
+
+// Env returns the environment to run go commands in.
+func (bc *BuildConfig) Env() []string {
+	env := os.Environ()
+	if bc.binDir != "" {
+		env = append(env, "PATH="+bc.binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	}
+	if goflags := bc.goFlags(); goflags != "" {
+		env = append(env, "GOFLAGS="+goflags)
+	}
+	if bc.GOOS != "" {
+		env = append(env, "GOOS="+bc.GOOS)
+	}
+	if bc.GOARCH != "" {
+		env = append(env, "GOARCH="+bc.GOARCH)
+	}
+	return env
+}
+
+func (bc *BuildConfig) goFlags() string {
+	return strings.TrimSpace(os.Getenv("GOFLAGS") + " " + bc.GoFlags)
+}
+
+// Command returns the go command with args, which must not be a build command,
+// run in dir.
+func (bc *BuildConfig) Command(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(bc.GoCmd, args...)
+	cmd.Dir = dir
+	cmd.Env = bc.Env()
+	return cmd
+}
+
+// BuildCommand returns the go build command subcommand, such as "build" or
+// "test", with the build flags and then args, run in dir.
+func (bc *BuildConfig) BuildCommand(dir, subcommand string, args ...string) *exec.Cmd {
+	return bc.Command(dir, append(append([]string{subcommand}, bc.BuildFlags()...), args...)...)
+}
+
+// Flags returns the gooptions flags reproducing the settings other than the go
+// command, which is specific to the machine.
+func (bc *BuildConfig) Flags() []string {
+	result := []string{}
+	add := func(name, value string) {
+		if value != "" {
+			result = append(result, "-"+name+"="+quoteFlagValue(value))
+		}
+	}
+	add("tags", bc.Tags)
+	add("goflags", bc.GoFlags)
+	add("goos", bc.GOOS)
+	add("goarch", bc.GOARCH)
+	return result
+}
+
+func quoteFlagValue(value string) string {
+	if strings.ContainsAny(value, " \t\"'") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// cacheFormat is changed when the cache's keys or entries change in ways the
+// version hash does not cover.
+const cacheFormat = "gooptions model cache 2"
+
+// Cache stores the models of struct types by the content of everything they are
+// reflected from, so types are not reflected on again until their package or
+// one of its dependencies changes.
+type Cache struct {
+	// Dir is the directory of the cache entries.
+	Dir string
+}
+
+// NewCache returns the cache in dir, or in the gooptions directory of the
+// user's cache directory if dir is empty. It returns nil if dir is "off".
+func NewCache(dir string) (*Cache, error) {
+	if dir == "off" {
+		return nil, nil
+	}
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(userCacheDir, "gooptions")
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+func (c *Cache) filename(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+"-models")
+}
+
+// Get returns the models stored with key, or false if there are none.
+func (c *Cache) Get(key string) ([]*model.StructType, bool) {
+	f, err := os.Open(c.filename(key))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	sts, err := model.DecodeStructTypes(f)
+	if err != nil {
+		return nil, false
+	}
+	return sts, true
+}
+
+// Put stores the models sts with key. The entry is written to a temporary file
+// first, so concurrent runs never read a partial entry.
+func (c *Cache) Put(key string, sts []*model.StructType) error {
+	filename := c.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(filename), "tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := model.WriteStructTypes(f, sts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// CacheKey returns the key of the models of the types of tp. It covers the
+// types, the go command settings and version, the gooptions version, and the
+// files of the package and of its dependencies, where dependencies from the
+// module cache are covered by their module versions.
+func CacheKey(bc *BuildConfig, tp *TargetPackage) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", cacheFormat)
+	fmt.Fprintf(h, "gooptions %s\n", gooptionsVersion())
+
+	env, err := goEnvs(bc, tp.Dir, "GOVERSION", "GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED", "GOEXPERIMENT")
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "env %q\n", env)
+	fmt.Fprintf(h, "flags %q\n", bc.Flags())
+
+	fmt.Fprintf(h, "package %s\n", tp.Path)
+	for _, tt := range tp.Types {
+		fmt.Fprintf(h, "type %s %s\n", tt.Package.Path, tt.Name)
+	}
+
+	args := []string{"-deps", "-json"}
+	if tp.InPackage() {
+		args = append(args, "-test")
+	}
+	cmd := bc.BuildCommand(tp.Dir, "list", append(args, ".")...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	// Files are hashed once, as the test variants of a package list them
+	// again.
+	hashed := map[string]bool{}
+	d := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var p listedPackage
+		if err := d.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if p.Standard {
+			continue
+		}
+		if m := p.Module; m != nil && m.Replace == nil && m.Version != "" {
+			fmt.Fprintf(h, "module %s %s\n", m.Path, m.Version)
+			continue
+		}
+		if m := p.Module; m != nil && m.Replace != nil && m.Replace.Version != "" {
+			fmt.Fprintf(h, "module %s %s\n", m.Replace.Path, m.Replace.Version)
+			continue
+		}
+
+		for _, files := range [][]string{p.GoFiles, p.CgoFiles, p.TestGoFiles, p.XTestGoFiles} {
+			for _, file := range files {
+				filename := filepath.Join(p.Dir, file)
+				if hashed[filename] {
+					continue
+				}
+				hashed[filename] = true
+				if err := hashSourceFile(h, filename); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// listedPackage is a package as printed by go list -json.
+type listedPackage struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	Standard   bool
+	Module     *struct {
+		Path    string
+		Version string
+		Replace *struct {
+			Path    string
+			Version string
+		}
+	}
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+}
+
+// hashSourceFile writes the name and content of the file filename to h. Files
+// generated by gooptions are left out, as the reflection programs are built
+// with stubs of them.
+func hashSourceFile(h io.Writer, filename string) error {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if model.IsGeneratedFile(src) {
+		return nil
+	}
+	sum := sha256.Sum256(src)
+	fmt.Fprintf(h, "file %s %x\n", filename, sum)
+	return nil
+}
+
+var (
+	versionOnce sync.Once
+	version     string
+)
+
+// gooptionsVersion returns a hash of the sources the models are made by, the
+// model package and the reflection programs.
+func gooptionsVersion() string {
+	versionOnce.Do(func() {
+		h := sha256.New()
+		filenames, err := fs.Glob(model.Source, "*")
+		if err != nil {
+			panic(err)
+		}
+		for _, filename := range filenames {
+			src, err := fs.ReadFile(model.Source, filename)
+			if err != nil {
+				panic(err)
+			}
+			fmt.Fprintf(h, "model %s %x\n", filename, sha256.Sum256(src))
+		}
+		for _, src := range []string{string(ModelReflectMainGo), ModelReflectVariableGoTemplate, ModelReflectTestGoTemplate} {
+			fmt.Fprintf(h, "%x\n", sha256.Sum256([]byte(src)))
+		}
+		version = hex.EncodeToString(h.Sum(nil))
+	})
+	return version
+}
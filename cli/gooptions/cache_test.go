@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// writeTestModule writes the module example.com/example with files, keyed by
+// their names, to a temporary directory and returns it.
+func writeTestModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/example\n\ngo 1.16\n"
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// loadTestPackage loads the package in dir with the type typeName.
+func loadTestPackage(t *testing.T, bc *BuildConfig, dir, typeName string) *TargetPackage {
+	t.Helper()
+	tps, err := LoadTargetPackages(bc, dir, []string{"."}, []string{typeName}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tps[0]
+}
+
+func TestCacheKey(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"user.go": "package example\n\ntype User struct {\n\tName string\n}\n",
+	})
+	bc, err := NewBuildConfig("", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := loadTestPackage(t, bc, dir, "User")
+
+	key, err := CacheKey(bc, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := CacheKey(bc, tp); err != nil || again != key {
+		t.Errorf("CacheKey() = %q, %v, then %q for the same package", key, err, again)
+	}
+
+	// Generated files are replaced by stubs when reflecting, so they do not
+	// change the models.
+	generated := model.GeneratedHeader + "\n\npackage example\n\nfunc WithName(string) {}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "user_options.go"), []byte(generated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CacheKey(bc, tp); err != nil || got != key {
+		t.Errorf("CacheKey() = %q, %v after generating a file, want %q", got, err, key)
+	}
+
+	tagged, err := NewBuildConfig("", "integration", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CacheKey(tagged, tp); err != nil || got == key {
+		t.Errorf("CacheKey() = %q, %v with build tags, want another key", got, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "user.go"), []byte("package example\n\ntype User struct {\n\tName string\n\tAge  int\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CacheKey(bc, tp); err != nil || got == key {
+		t.Errorf("CacheKey() = %q, %v after editing the package, want another key", got, err)
+	}
+}
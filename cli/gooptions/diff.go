@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around the changes of a hunk.
+const diffContext = 3
+
+// diffOp is a line of an edit script: kept (' '), deleted ('-') or inserted
+// ('+'). aPos and bPos are the numbers of lines of a and b before it.
+type diffOp struct {
+	kind       byte
+	line       string
+	aPos, bPos int
+}
+
+// UnifiedDiff returns the unified diff turning a into b, which are named aName
+// and bName in its header, or "" if they are equal. A last line without a
+// newline is marked as such, as by diff.
+func UnifiedDiff(aName, bName string, a, b []byte) string {
+	if string(a) == string(b) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(a)), splitLines(string(b)))
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "--- %s\n+++ %s\n", aName, bName)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// A hunk extends from the context before a change to the context after
+		// the last change whose context touches the context of the previous
+		// one.
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ops) && j <= end+2*diffContext+1; j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			}
+		}
+		i = end + 1
+		end += diffContext
+		if end >= len(ops) {
+			end = len(ops) - 1
+		}
+
+		aLen, bLen := 0, 0
+		for _, op := range ops[start : end+1] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(ops[start].aPos, aLen), hunkRange(ops[start].bPos, bLen))
+		for _, op := range ops[start : end+1] {
+			fmt.Fprintf(sb, "%c%s", op.kind, op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func hunkRange(pos, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", pos+1)
+	}
+	return fmt.Sprintf("%d,%d", pos+1, length)
+}
+
+// splitLines returns the lines of s with their newlines, so a last line
+// without one differs from the same line with one.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edit script turning a into b. The lines a and b start
+// and end with are kept, and the lines between them are compared by
+// shortestEdit.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := []diffOp{}
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{' ', a[i], i, i})
+	}
+	for _, op := range shortestEdit(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		op.aPos += prefix
+		op.bPos += prefix
+		ops = append(ops, op)
+	}
+	for i, j := len(a)-suffix, len(b)-suffix; i < len(a); i, j = i+1, j+1 {
+		ops = append(ops, diffOp{' ', a[i], i, j})
+	}
+	return ops
+}
+
+// shortestEdit returns the edit script turning a into b with the fewest
+// deletions and insertions, following E. Myers, "An O(ND) Difference Algorithm
+// and Its Variations". Only the furthest reaching paths of each number of edits
+// d are kept, so it takes O(d²) memory rather than O(len(a)·len(b)).
+func shortestEdit(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+
+	// v[offset+k] is the furthest x reached on diagonal k = x-y, and trace[d]
+	// holds v for diagonals -d-1 to d+1 before d edits are considered.
+	v := make([]int, 2*offset+1)
+	trace := [][]int{}
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+		done := false
+		for k := -d; k <= d; k += 2 {
+			x := v[offset+k-1] + 1
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		if done {
+			break
+		}
+	}
+
+	// The edits are found backwards from the end of a and b.
+	ops := []diffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		prev := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev(k-1) < prev(k+1)) {
+			prevK = k + 1
+		}
+		prevX := prev(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x], x, y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{'+', b[y-1], x, y - 1})
+		} else {
+			ops = append(ops, diffOp{'-', a[x-1], x - 1, y})
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(lines ...string) []byte {
+		return []byte(strings.Join(lines, "\n") + "\n")
+	}
+	numbered := lines("1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16")
+
+	for _, test := range []struct {
+		name         string
+		aName, bName string
+		a, b         []byte
+		want         string
+	}{
+		{
+			name:  "equal",
+			aName: "a.go", bName: "a.go",
+			a: numbered, b: numbered,
+			want: "",
+		},
+		{
+			name:  "change",
+			aName: "a.go", bName: "a.go",
+			a: numbered,
+			b: lines("1", "2", "3", "4", "5", "6", "seven", "8", "9", "10", "11", "12", "13", "14", "15", "16"),
+			want: `--- a.go
++++ a.go
+@@ -4,7 +4,7 @@
+ 4
+ 5
+ 6
+-7
++seven
+ 8
+ 9
+ 10
+`,
+		},
+		{
+			name:  "changes merged into a hunk",
+			aName: "a.go", bName: "a.go",
+			a: numbered,
+			b: lines("1", "two", "3", "4", "5", "6", "7", "8", "nine", "10", "11", "12", "13", "14", "15", "16"),
+			want: `--- a.go
++++ a.go
+@@ -1,12 +1,12 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
++nine
+ 10
+ 11
+ 12
+`,
+		},
+		{
+			name:  "changes in separate hunks",
+			aName: "a.go", bName: "a.go",
+			a: numbered,
+			b: lines("1", "two", "3", "4", "5", "6", "7", "8", "9", "ten", "11", "12", "13", "14", "15", "16"),
+			want: `--- a.go
++++ a.go
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -7,7 +7,7 @@
+ 7
+ 8
+ 9
+-10
++ten
+ 11
+ 12
+ 13
+`,
+		},
+		{
+			name:  "new file",
+			aName: "/dev/null", bName: "a.go",
+			b: lines("package a", "", "const A = 1"),
+			want: `--- /dev/null
++++ a.go
+@@ -0,0 +1,3 @@
++package a
++
++const A = 1
+`,
+		},
+		{
+			name:  "deleted file",
+			aName: "a.go", bName: "/dev/null",
+			a: lines("package a"),
+			want: `--- a.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package a
+`,
+		},
+		{
+			name:  "missing newline at end of file",
+			aName: "a.go", bName: "a.go",
+			a:    []byte("package a\n\nconst A = 1"),
+			b:    lines("package a", "", "const A = 1"),
+			want: "--- a.go\n+++ a.go\n@@ -1,3 +1,3 @@\n package a\n \n-const A = 1\n\\ No newline at end of file\n+const A = 1\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnifiedDiff(test.aName, test.bName, test.a, test.b); got != test.want {
+				t.Errorf("UnifiedDiff() = \n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// GenerateDirective annotates the struct types to generate options for when
+// no types are given on the command line. It is followed by optional settings
+// overriding the command line flags for the type, such as
+//
+//	//gooptions:generate prefix=Set option=UserOption
+const GenerateDirective = "//gooptions:generate"
+
+// Directive is a GenerateDirective comment.
+type Directive struct {
+	// Settings by name, in the form name=value, or name alone for true.
+	Settings map[string]string
+}
+
+// directiveSettings are the settings a directive accepts.
+var directiveSettings = map[string]bool{
+	"option":      true,
+	"prefix":      true,
+	"initialisms": true,
+	"embedded":    true,
+	"copylocks":   true,
+}
+
+// ParseDirective parses the comment text. It returns nil if text is not a
+// GenerateDirective.
+func ParseDirective(text string) (*Directive, error) {
+	if text != GenerateDirective && !strings.HasPrefix(text, GenerateDirective+" ") && !strings.HasPrefix(text, GenerateDirective+"\t") {
+		return nil, nil
+	}
+
+	d := &Directive{Settings: map[string]string{}}
+	for _, setting := range strings.Fields(strings.TrimPrefix(text, GenerateDirective)) {
+		name, value := setting, "true"
+		if i := strings.Index(setting, "="); i >= 0 {
+			name, value = setting[:i], setting[i+1:]
+		}
+		if !directiveSettings[name] {
+			return nil, fmt.Errorf("unknown setting %q in %s", name, GenerateDirective)
+		}
+		d.Settings[name] = value
+	}
+	return d, nil
+}
+
+// findDirective returns the directive in the doc comment of spec, or of its
+// declaration gd if it only declares spec, or nil if there is none.
+func findDirective(fset *token.FileSet, gd *ast.GenDecl, spec *ast.TypeSpec) (*Directive, error) {
+	docs := []*ast.CommentGroup{spec.Doc}
+	if len(gd.Specs) == 1 {
+		docs = append(docs, gd.Doc)
+	}
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, c := range doc.List {
+			d, err := ParseDirective(c.Text)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", fset.Position(c.Pos()), err)
+			}
+			if d != nil {
+				return d, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// setting returns the value of the setting name of d, which may be nil.
+func (d *Directive) setting(name string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	value, ok := d.Settings[name]
+	return value, ok
+}
+
+// Apply overrides the options o with the settings of d.
+func (d *Directive) Apply(o *model.Options) error {
+	if d == nil {
+		return nil
+	}
+
+	if value, ok := d.Settings["option"]; ok {
+		o.OptionName = value
+	}
+	if value, ok := d.Settings["prefix"]; ok {
+		o.OptionPrefix = value
+	}
+	if value, ok := d.Settings["initialisms"]; ok {
+		o.Initialisms = append(o.Initialisms, strings.Split(value, ",")...)
+	}
+	if value, ok := d.Settings["embedded"]; ok {
+		embedded, err := model.ParseEmbeddedMode(value)
+		if err != nil {
+			return err
+		}
+		o.Embedded = embedded
+	}
+	if value, ok := d.Settings["copylocks"]; ok {
+		copyLocks, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("bad copylocks setting %q: %v", value, err)
+		}
+		o.CopyLocks = copyLocks
+	}
+	return nil
+}
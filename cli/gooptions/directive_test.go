@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+func TestParseDirective(t *testing.T) {
+	for _, test := range []struct {
+		text    string
+		want    *Directive
+		wantErr bool
+	}{
+		{text: "// User is a user."},
+		{text: "//gooptions:generated"},
+		{text: "//gooptions:generate", want: &Directive{Settings: map[string]string{}}},
+		{
+			text: "//gooptions:generate prefix=Set option=UserOption\tcopylocks",
+			want: &Directive{Settings: map[string]string{"prefix": "Set", "option": "UserOption", "copylocks": "true"}},
+		},
+		{text: "//gooptions:generate suffix=Set", wantErr: true},
+	} {
+		t.Run(test.text, func(t *testing.T) {
+			got, err := ParseDirective(test.text)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseDirective() error = %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseDirective() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDirectiveApply(t *testing.T) {
+	d, err := ParseDirective("//gooptions:generate option=UserOption prefix=Set initialisms=SKU embedded=promote copylocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := model.NewOptions()
+	initialisms := len(o.Initialisms)
+	if err := d.Apply(o); err != nil {
+		t.Fatal(err)
+	}
+	if o.OptionName != "UserOption" || o.OptionPrefix != "Set" {
+		t.Errorf("Apply() set OptionName %q and OptionPrefix %q, want UserOption and Set", o.OptionName, o.OptionPrefix)
+	}
+	if len(o.Initialisms) != initialisms+1 || o.Initialisms[initialisms] != "SKU" {
+		t.Errorf("Apply() set Initialisms %v, want SKU added", o.Initialisms)
+	}
+	if o.Embedded != model.EmbeddedPromote {
+		t.Errorf("Apply() set Embedded %v, want %v", o.Embedded, model.EmbeddedPromote)
+	}
+	if !o.CopyLocks {
+		t.Error("Apply() did not set CopyLocks")
+	}
+
+	var none *Directive
+	o = model.NewOptions()
+	if err := none.Apply(o); err != nil || !reflect.DeepEqual(o, model.NewOptions()) {
+		t.Errorf("Apply() of no directive = %v and changed the options to %+v", err, o)
+	}
+
+	for _, text := range []string{
+		"//gooptions:generate embedded=deep",
+		"//gooptions:generate copylocks=maybe",
+	} {
+		d, err := ParseDirective(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Apply(model.NewOptions()); err == nil {
+			t.Errorf("Apply() of %q succeeded, want an error", text)
+		}
+	}
+}
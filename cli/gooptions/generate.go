@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// PackageResult is the outcome of generating the options of the types of a
+// package.
+type PackageResult struct {
+	Package *TargetPackage
+
+	// Diagnostics about the fields no option is generated for, prefixed by
+	// the name of their type.
+	Diagnostics []string
+
+	// Pending are the paths of the files that would be written in dry run
+	// mode.
+	Pending []string
+
+	// Diffs are the unified diffs of the files out of date in check mode.
+	Diffs []string
+
+	// Err is the error generating the options, with the exit code it is
+	// reported with.
+	Err      error
+	ExitCode int
+}
+
+// GeneratePackages calls generate for each of tps, running at most parallel
+// calls at once, and returns the results in the order of tps.
+func GeneratePackages(parallel int, tps []*TargetPackage, generate func(*TargetPackage) *PackageResult) []*PackageResult {
+	results := make([]*PackageResult, len(tps))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallel)
+	for i, tp := range tps {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, tp *TargetPackage) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = generate(tp)
+		}(i, tp)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// GeneratePackage builds the reflection program for the types of tp and writes
+// their options.
+func GeneratePackage(bc *BuildConfig, f *Flags, cache *Cache, sourceDir string, tp *TargetPackage, overlay map[string][]byte) *PackageResult {
+	result := &PackageResult{Package: tp}
+	fail := func(err error, exitCode int) *PackageResult {
+		result.Err = err
+		result.ExitCode = exitCode
+		return result
+	}
+
+	options := model.NewOptions()
+	options.OptionName = f.OptionName
+	options.OptionPrefix = f.OptionPrefix
+	if f.Initialisms != "" {
+		options.Initialisms = append(options.Initialisms, strings.Split(f.Initialisms, ",")...)
+	}
+	options.Embedded = f.Embedded
+	options.CopyLocks = f.CopyLocks
+	options.BuildFlags = bc.Flags()
+	options.Force = f.Force
+	options.License = f.License
+	options.Templates = f.Templates
+	if f.CommandLine {
+		options.CommandLine = f.CommandLineString()
+	}
+
+	// The options of several types of a package are told apart by the type
+	// name, unless they were explicitly named otherwise.
+	if len(tp.Types) > 1 {
+		if !f.set["option"] {
+			options.OptionName = model.TypePlaceholder + options.OptionName
+		}
+		if !f.set["prefix"] {
+			options.OptionPrefix += model.TypePlaceholder
+		}
+	}
+
+	typeOptions := []*model.Options{}
+	for _, tt := range tp.Types {
+		o := *options
+		o.Initialisms = append([]string{}, options.Initialisms...)
+		if err := tt.Directive.Apply(&o); err != nil {
+			return fail(fmt.Errorf("%s: %v", tt.Name, err), 3)
+		}
+
+		to := o.ForType(tt.Name)
+		to.TestFile = tt.Test
+		to.BuildConstraint = model.AndConstraints(tt.BuildConstraint, f.Constraint)
+		typeOptions = append(typeOptions, to)
+	}
+
+	// Files are written next to the package's files, and -dest is relative to
+	// the source directory.
+	outputDir := tp.Dir
+	if f.DestinationPath != "" {
+		outputDir = sourceDir
+	}
+
+	// The models are cached by the source they are reflected from, so the
+	// reflection program is not built when none of it changed. Without a key,
+	// errors are left to the build of the reflection program to report.
+	key, err := CacheKey(bc, tp)
+	if err != nil {
+		key = ""
+	}
+	modelStructTypes, err := ReflectStructTypes(bc, cache, tp, key, overlay)
+	if err != nil {
+		return fail(fmt.Errorf("failed to generate model from reflection: %v", err), 4)
+	}
+
+	if err := ResolvePackageNames(bc, tp, modelStructTypes); err != nil {
+		return fail(fmt.Errorf("failed to load imported package information: %v", err), 4)
+	}
+
+	// Doc comments are not known by reflection, and are taken from the
+	// source.
+	for i, st := range modelStructTypes {
+		for _, field := range st.Fields {
+			field.Doc = tp.Types[i].FieldDocs[field.Name]
+		}
+	}
+
+	// Files are named after their type, or after the package for the combined
+	// file.
+	files := []*model.File{}
+	names := []string{}
+	if f.Combined {
+		tt := tp.Types[0]
+		file := model.NewFile(tt.Package, typeOptions, modelStructTypes)
+		result.addDiagnostics(file.Models)
+		files = append(files, file)
+		names = append(names, tt.Package.Name)
+	} else {
+		for i, tt := range tp.Types {
+			m := model.NewModel(typeOptions[i], tt.Package, modelStructTypes[i])
+			result.addDiagnostics([]*model.Model{m})
+			files = append(files, m.File())
+			names = append(names, tt.Name)
+		}
+	}
+
+	// The sources of plugins and of gooptions are handled alike: they are
+	// compared in check mode, and otherwise collected to be written once they
+	// are all generated. Up to date files of gooptions are not rendered again.
+	srcs := []*GeneratedSource{}
+	if f.Plugin != "" {
+		if srcs, err = RunPlugin(f.Plugin, f.PluginParam, outputDir, files, names); err != nil {
+			return fail(err, 5)
+		}
+	} else {
+		for i, file := range files {
+			path := file.Models[0].Options.OutputPath(names[i], outputDir, f.DestinationPath)
+			if !f.Check && f.DestinationPath != "-" && model.UpToDate(file, path) {
+				continue
+			}
+			src, err := model.RenderFile(file)
+			if err != nil {
+				return fail(err, 5)
+			}
+			srcs = append(srcs, &GeneratedSource{Path: path, Src: src, File: file})
+		}
+	}
+
+	pending := []*GeneratedSource{}
+	for _, src := range srcs {
+		switch {
+		case f.Check:
+			diff, err := CheckSource(src)
+			if err != nil {
+				return fail(err, 5)
+			}
+			if diff != "" {
+				result.Diffs = append(result.Diffs, diff)
+			}
+
+		case f.DestinationPath == "-":
+			// Only a single file is written to stdout, see Flags.CheckTypes.
+			if _, err := os.Stdout.Write(src.Src); err != nil {
+				return fail(err, 5)
+			}
+
+		default:
+			// Unchanged files are left alone.
+			if got, err := ioutil.ReadFile(src.Path); err == nil && bytes.Equal(got, src.Src) {
+				continue
+			}
+			checkOverwrite := model.CheckOverwrite
+			if src.File == nil {
+				checkOverwrite = model.CheckOverwriteCode
+			}
+			if err := checkOverwrite(src.Path, f.Force); err != nil {
+				return fail(forceHint(err), 5)
+			}
+			pending = append(pending, src)
+		}
+	}
+
+	// Files written to the package are type-checked with it first, so the
+	// package is not left broken. Errors are reported as they would be
+	// without -dry-run.
+	if f.TypeCheck {
+		inPackage := []*GeneratedSource{}
+		for _, src := range pending {
+			if filepath.Dir(src.Path) == tp.Dir && strings.HasSuffix(src.Path, ".go") {
+				inPackage = append(inPackage, src)
+			}
+		}
+		if len(inPackage) > 0 {
+			if err := TypeCheck(bc, tp, overlay, inPackage); err != nil {
+				return fail(err, 5)
+			}
+		}
+	}
+
+	for _, src := range pending {
+		if f.DryRun {
+			result.Pending = append(result.Pending, src.Path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(src.Path), 0777); err != nil {
+			return fail(err, 5)
+		}
+		if err := model.WriteFileAtomic(src.Path, src.Src); err != nil {
+			return fail(err, 5)
+		}
+	}
+
+	if len(result.Diffs) > 0 {
+		return fail(fmt.Errorf("%v generated files are out of date", len(result.Diffs)), 1)
+	}
+	return result
+}
+
+// forceHint adds the flag overriding the error to a *model.NotGeneratedError.
+func forceHint(err error) error {
+	if _, ok := err.(*model.NotGeneratedError); ok {
+		return fmt.Errorf("%v (use -force to overwrite it)", err)
+	}
+	return err
+}
+
+// CheckSource returns the unified diff from the file on disk to src, or "" if
+// they are the same. Nothing is written.
+func CheckSource(src *GeneratedSource) (string, error) {
+	oldName := src.Path
+	got, err := ioutil.ReadFile(src.Path)
+	if os.IsNotExist(err) {
+		oldName = "/dev/null"
+	} else if err != nil {
+		return "", err
+	}
+
+	return UnifiedDiff(oldName, src.Path, got, src.Src), nil
+}
+
+// ReflectStructTypes returns the models of the types of tp from cache, or else
+// builds the reflection program and stores its models in cache, which may be
+// nil. key is the CacheKey of tp, or "" if it is unknown and the models are not
+// cached.
+func ReflectStructTypes(bc *BuildConfig, cache *Cache, tp *TargetPackage, key string, overlay map[string][]byte) ([]*model.StructType, error) {
+	if cache == nil || key == "" {
+		return BuildRunReflectProgram(bc, tp, overlay)
+	}
+	if sts, ok := cache.Get(key); ok && len(sts) == len(tp.Types) {
+		return sts, nil
+	}
+
+	sts, err := BuildRunReflectProgram(bc, tp, overlay)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Put(key, sts); err != nil {
+		fmt.Fprintf(os.Stderr, "gooptions: failed to cache models of %v: %v\n", tp.Path, err)
+	}
+	return sts, nil
+}
+
+func (r *PackageResult) addDiagnostics(models []*model.Model) {
+	for _, m := range models {
+		for _, diagnostic := range m.Diagnostics {
+			r.Diagnostics = append(r.Diagnostics, fmt.Sprintf("%s: %s", m.StructType.Name, diagnostic))
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGeneratePackageCheckCopy(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"user.go": "package example\n\nimport \"time\"\n\ntype User struct {\n\tName    string\n\tTimeout time.Duration\n}\n",
+	})
+	bc, err := NewBuildConfig("", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFlags([]string{"-type", "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := GeneratePackage(bc, f, nil, dir, loadTestPackage(t, bc, dir, "User"), nil)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	// The generated files do not depend on where the package is, so they are
+	// up to date in a copy of it.
+	other := filepath.Join(t.TempDir(), "other")
+	if err := copyDir(other, dir); err != nil {
+		t.Fatal(err)
+	}
+	f.Check = true
+	result = GeneratePackage(bc, f, nil, other, loadTestPackage(t, bc, other, "User"), nil)
+	if result.Err != nil || len(result.Diffs) > 0 {
+		t.Errorf("GeneratePackage() with -check in a copy = %v, diffs %q", result.Err, result.Diffs)
+	}
+}
+
+// copyDir copies the files of the directory src to the new directory dst.
+func copyDir(dst, src string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0777)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), b, info.Mode())
+	})
+}
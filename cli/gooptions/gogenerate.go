@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GoGenerate is the go:generate directive gooptions is run by, as described by
+// the environment go generate sets.
+type GoGenerate struct {
+	// File is the base name of the file with the directive, $GOFILE.
+	File string
+
+	// Line is the line number of the directive, $GOLINE.
+	Line int
+
+	// Package is the name of the package of File, $GOPACKAGE.
+	Package string
+}
+
+// GoGenerateFromEnv returns the go:generate directive gooptions is run by, or
+// nil if it is not run by go generate.
+func GoGenerateFromEnv() (*GoGenerate, error) {
+	file := os.Getenv("GOFILE")
+	if file == "" {
+		return nil, nil
+	}
+
+	line, err := strconv.Atoi(os.Getenv("GOLINE"))
+	if err != nil {
+		return nil, fmt.Errorf("bad GOLINE %q: %v", os.Getenv("GOLINE"), err)
+	}
+
+	return &GoGenerate{
+		File:    file,
+		Line:    line,
+		Package: os.Getenv("GOPACKAGE"),
+	}, nil
+}
+
+// Test reports whether the directive is in a _test.go file.
+func (g *GoGenerate) Test() bool {
+	return strings.HasSuffix(g.File, "_test.go")
+}
+
+// TypeName returns the name of the type declared directly below the directive
+// in the file in dir, or "" if the directive is not above a type declaration,
+// such as a directive generating the annotated types of the package.
+func (g *GoGenerate) TypeName(dir string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, g.File), nil, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	if g.Package != "" && file.Name.Name != g.Package {
+		return "", fmt.Errorf("%s is in package %s, not %s", g.File, file.Name.Name, g.Package)
+	}
+
+	line := func(pos token.Pos) int {
+		return fset.Position(pos).Line
+	}
+
+	for _, decl := range file.Decls {
+		if line(decl.Pos()) <= g.Line {
+			continue
+		}
+
+		// The directive is either the line above the declaration or its doc
+		// comment, or a line of the doc comment.
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE || len(gd.Specs) != 1 {
+			return "", nil
+		}
+		start := line(gd.Pos())
+		if gd.Doc != nil {
+			start = line(gd.Doc.Pos())
+		}
+		if start > g.Line+1 {
+			return "", nil
+		}
+
+		spec := gd.Specs[0].(*ast.TypeSpec)
+		if !isStructTypeSpec(spec) {
+			return "", fmt.Errorf("%s:%d: options can only be generated for non-generic struct types, not %s", g.File, line(spec.Pos()), spec.Name.Name)
+		}
+		return spec.Name.Name, nil
+	}
+	return "", nil
+}
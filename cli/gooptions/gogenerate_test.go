@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const goGenerateTestFile = `package example
+
+//go:generate gooptions
+type User struct {
+	Name string
+}
+
+// Account is an account.
+//
+//go:generate gooptions
+type Account struct {
+	ID int
+}
+
+//go:generate gooptions
+
+type Detached struct{}
+
+//go:generate gooptions
+type ID int
+
+//go:generate gooptions
+func f() {}
+`
+
+func TestGoGenerateTypeName(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "example.go"), []byte(goGenerateTestFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		line    int
+		want    string
+		wantErr bool
+	}{
+		{line: 3, want: "User"},
+		{line: 10, want: "Account"},
+		{line: 15, want: ""},
+		{line: 19, wantErr: true},
+		{line: 22, want: ""},
+	} {
+		g := &GoGenerate{File: "example.go", Line: test.line, Package: "example"}
+		got, err := g.TypeName(dir)
+		if (err != nil) != test.wantErr {
+			t.Errorf("TypeName() of line %d error = %v, want error %v", test.line, err, test.wantErr)
+		}
+		if got != test.want {
+			t.Errorf("TypeName() of line %d = %q, want %q", test.line, got, test.want)
+		}
+	}
+
+	g := &GoGenerate{File: "example.go", Line: 3, Package: "other"}
+	if _, err := g.TypeName(dir); err == nil {
+		t.Error("TypeName() in another package succeeded, want an error")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadedPackage is a package listed by the go command with its parsed files.
+type loadedPackage struct {
+	// ID is the import path of the package, followed by the test binary for
+	// the test variants, such as "example.com/p [example.com/p.test]".
+	ID string
+
+	PkgPath string
+	Name    string
+
+	Syntax []*ast.File
+}
+
+// loadPackages lists the packages matching patterns in cwd, and with tests
+// their test variants, external test packages and test main packages, and
+// parses their files with comments into fset, unless fset is nil. The go
+// command of bc is run rather than the one in PATH, so the packages are loaded
+// by the selected toolchain. Packages with errors are listed with the files
+// that could be parsed.
+func loadPackages(bc *BuildConfig, cwd string, fset *token.FileSet, tests bool, patterns ...string) ([]*loadedPackage, error) {
+	args := []string{"-e", "-json"}
+	if tests {
+		args = append(args, "-test")
+	}
+	args = append(args, "--")
+	args = append(args, patterns...)
+
+	cmd := bc.BuildCommand(cwd, "list", args...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*loadedPackage{}
+	d := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var lp listedPackage
+		if err := d.Decode(&lp); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		p := &loadedPackage{
+			ID:      lp.ImportPath,
+			PkgPath: lp.ImportPath,
+			Name:    lp.Name,
+		}
+		if i := strings.Index(p.PkgPath, " ["); i >= 0 {
+			p.PkgPath = p.PkgPath[:i]
+		}
+		if fset == nil {
+			result = append(result, p)
+			continue
+		}
+		for _, name := range append(append([]string{}, lp.GoFiles...), lp.CgoFiles...) {
+			filename := name
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(lp.Dir, name)
+			}
+			file, _ := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+			if file != nil {
+				p.Syntax = append(p.Syntax, file)
+			}
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
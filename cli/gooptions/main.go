@@ -3,8 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"go/build/constraint"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/shipyardapp/gooptions/model"
 )
@@ -20,57 +24,379 @@ func main() {
 		exit(fmt.Errorf("failed to get cwd: %v", err), 2)
 	}
 
-	sourceDir := filepath.Join(cwd, f.SourceDir)
+	if err := f.ReadLicense(cwd); err != nil {
+		exit(err, 1)
+	}
+	if err := f.ReadTemplates(cwd); err != nil {
+		exit(err, 1)
+	}
 
-	modelPackage, err := NewModelPackageReflect(cwd, sourceDir)
+	bc, err := NewBuildConfig(f.GoCmd, f.Tags, f.GoFlags, f.GOOS, f.GOARCH)
 	if err != nil {
-		exit(fmt.Errorf("failed to load source package information: %v", err), 3)
+		exit(err, 2)
 	}
 
-	modelStructType, err := BuildRunReflectProgram(modelPackage, f.Type)
+	// A go:generate directive directly above a type generates the type's
+	// options, which are written to the directive's directory, the working
+	// directory of go generate.
+	if f.Type == "" && !f.All && !f.set["source"] && !f.set["patterns"] {
+		gg, err := GoGenerateFromEnv()
+		if err != nil {
+			exit(err, 1)
+		}
+		if gg != nil {
+			typeName, err := gg.TypeName(cwd)
+			if err != nil {
+				exit(fmt.Errorf("failed to find the type of the go:generate directive: %v", err), 3)
+			}
+			if typeName != "" {
+				f.Type = typeName
+				f.Tests = f.Tests || gg.Test()
+			}
+		}
+	}
+
+	sourceDir := f.SourceDir
+	if !filepath.IsAbs(sourceDir) {
+		sourceDir = filepath.Join(cwd, sourceDir)
+	}
+
+	// Packages are loaded in the source directory, so they are resolved in its
+	// module rather than the module of the current working directory.
+	targetPackages, err := LoadTargetPackages(bc, sourceDir, f.Patterns, f.TypeNames(), f.Tests)
 	if err != nil {
-		exit(fmt.Errorf("failed to generate model from reflection: %v", err), 4)
+		exit(fmt.Errorf("failed to load source package information: %v", err), 3)
+	}
+	if len(targetPackages) > 1 && f.DestinationPath != "" {
+		exit(fmt.Errorf("-dest cannot be used for %v packages", len(targetPackages)), 1)
+	}
+	for _, tp := range targetPackages {
+		if err := f.CheckTypes(tp); err != nil {
+			exit(fmt.Errorf("%v: %v", tp.Path, err), 1)
+		}
 	}
 
-	modelModel := model.NewModel(
-		model.NewOptions(),
-		modelPackage,
-		modelStructType,
-	)
+	// Previously generated files may no longer compile, so they are replaced
+	// by stubs while building the reflection programs. The files of all the
+	// packages are replaced, as the packages may import each other.
+	dirs := []string{}
+	for _, tp := range targetPackages {
+		dirs = append(dirs, tp.Dir)
+	}
+	overlay, err := GeneratedFilesOverlay(dirs...)
+	if err != nil {
+		exit(fmt.Errorf("failed to read generated files: %v", err), 4)
+	}
 
-	err = model.Generate(modelModel, f.Type, sourceDir, f.DestinationPath)
+	cache, err := NewCache(f.CacheDir)
 	if err != nil {
-		exit(err, 5)
+		exit(fmt.Errorf("failed to open cache: %v", err), 2)
+	}
+
+	results := GeneratePackages(f.Parallel, targetPackages, func(tp *TargetPackage) *PackageResult {
+		return GeneratePackage(bc, f, cache, sourceDir, tp, overlay)
+	})
+
+	if len(results) == 1 {
+		result := results[0]
+		printDiagnostics(result)
+		if result.Err != nil {
+			exit(result.Err, result.ExitCode)
+		}
+		return
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		printDiagnostics(result)
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL\t%s\t%v\n", result.Package.Path, result.Err)
+			if result.ExitCode > exitCode {
+				exitCode = result.ExitCode
+			}
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "ok  \t%s\t%s\n", result.Package.Path, strings.Join(result.Package.TypeNames(), ","))
+	}
+	os.Exit(exitCode)
+}
+
+func printDiagnostics(result *PackageResult) {
+	for _, diagnostic := range result.Diagnostics {
+		fmt.Fprintf(os.Stderr, "gooptions: %s\n", diagnostic)
+	}
+	for _, path := range result.Pending {
+		fmt.Println(path)
+	}
+	for _, diff := range result.Diffs {
+		fmt.Print(diff)
 	}
 }
 
 type Flags struct {
 	SourceDir       string
 	Type            string
+	All             bool
+	Combined        bool
 	DestinationPath string
+	OptionName      string
+	OptionPrefix    string
+	Initialisms     string
+	Embedded        model.EmbeddedMode
+	CopyLocks       bool
+	Tests           bool
+	Tags            string
+	GoFlags         string
+	GoCmd           string
+	GOOS            string
+	GOARCH          string
+	Parallel        int
+	Check           bool
+	DryRun          bool
+	Force           bool
+	TypeCheck       bool
+	CacheDir        string
+	LicenseFile     string
+	Constraint      string
+	CommandLine     bool
+	Plugin          string
+	PluginParam     string
+
+	// License is the text of LicenseFile, read by ReadLicense.
+	License string
+
+	// TemplateFiles are the files of the templates replacing the blocks of
+	// the default template, in order.
+	TemplateFiles []string
+
+	// Templates are the contents of TemplateFiles, read by ReadTemplates.
+	Templates []*model.Template
+
+	// Patterns of the packages to generate options for, resolved in
+	// SourceDir.
+	Patterns []string
+
+	// set records the flags given on the command line, and "patterns" if
+	// package patterns are given.
+	set map[string]bool
+
+	// args are the command line arguments the flags were parsed from.
+	args []string
 }
 
 func NewFlags(args []string) (*Flags, error) {
 	f := &Flags{
 		SourceDir:       ".",
 		Type:            "",
+		All:             false,
+		Combined:        false,
 		DestinationPath: "",
+		OptionName:      "Option",
+		OptionPrefix:    "With",
+		Initialisms:     "",
+		Embedded:        model.EmbeddedValue,
+		CopyLocks:       false,
+		Tests:           false,
+		Tags:            "",
+		GoFlags:         "",
+		GoCmd:           "go",
+		GOOS:            "",
+		GOARCH:          "",
+		Parallel:        runtime.GOMAXPROCS(0),
+		Check:           false,
+		DryRun:          false,
+		Force:           false,
+		TypeCheck:       true,
+		CacheDir:        os.Getenv("GOOPTIONSCACHE"),
+		LicenseFile:     "",
+		Constraint:      "",
+		CommandLine:     false,
+		Plugin:          "",
+		PluginParam:     "",
+		Patterns:        []string{"."},
+		set:             map[string]bool{},
+		args:            args,
 	}
 
 	fs := flag.NewFlagSet("gooptions", flag.ExitOnError)
 
-	fs.StringVar(&f.SourceDir, "source", f.SourceDir, "source package to generate options for types in the package")
-	fs.StringVar(&f.Type, "type", f.Type, "name of struct type to generate options for")
-	fs.StringVar(&f.DestinationPath, "dest", "", `destination file path to write options file to (default: empty value means "<os.Getwd()>/<strings.ToLower(type)>_options.go")`)
+	fs.StringVar(&f.SourceDir, "source", f.SourceDir, "source package to generate options for types in the package, or the directory the package patterns given as arguments, such as ./..., are resolved in")
+	fs.StringVar(&f.Type, "type", f.Type, `comma separated names of struct types to generate options for, or patterns such as "*Config" matching the names of the package's struct types (default: the type declared below the go:generate directive running gooptions, or else the types annotated with //gooptions:generate)`)
+	fs.BoolVar(&f.All, "all", f.All, "generate options for every struct type of the package")
+	fs.BoolVar(&f.Combined, "combined", f.Combined, `write the options of all the types to a single file (default: "<package>_options.go")`)
+	fs.StringVar(&f.DestinationPath, "dest", "", `destination file path to write options file to, or "-" for stdout (default: empty value means "<os.Getwd()>/<strings.ToLower(type)>_options.go", or "_options_test.go" for types declared in test files)`)
+
+	fs.StringVar(&f.OptionName, "option", f.OptionName, `name of the generated option type, where "{Type}" is replaced by the struct type name (default "{Type}Option" for several types)`)
+	fs.StringVar(&f.OptionPrefix, "prefix", f.OptionPrefix, `prefix of the generated option function names, where "{Type}" is replaced by the struct type name (default "With{Type}" for several types)`)
+	fs.StringVar(&f.Initialisms, "initialisms", f.Initialisms, "comma separated initialisms to write in a single case in generated names in addition to the common ones (e.g. OAuth,SKU)")
+
+	fs.Func("embedded", `options for embedded fields: "value" for the whole value, "promote" for each promoted field, or "skip" (default "value")`, func(s string) error {
+		var err error
+		f.Embedded, err = model.ParseEmbeddedMode(s)
+		return err
+	})
+
+	fs.BoolVar(&f.Check, "check", f.Check, "print the unified diff of the generated files to the files on disk and exit with status 1 if they differ, without writing anything")
+
+	fs.BoolVar(&f.DryRun, "dry-run", f.DryRun, "print the paths of the files that would be written, without writing them")
+
+	fs.BoolVar(&f.Force, "force", f.Force, "overwrite destination files that were not generated by gooptions")
+
+	fs.BoolVar(&f.TypeCheck, "typecheck", f.TypeCheck, "type-check the generated files with their package before writing them, and write nothing if they do not compile")
+
+	fs.IntVar(&f.Parallel, "parallel", f.Parallel, "maximum number of packages to generate options for at once")
+
+	fs.StringVar(&f.CacheDir, "cache", f.CacheDir, `directory of the cache of struct models, or "off" to always reflect on the types (default: $GOOPTIONSCACHE, or else the gooptions directory of the user cache directory)`)
+
+	fs.StringVar(&f.LicenseFile, "license", f.LicenseFile, "file whose text is written as comment lines at the top of the generated files")
+	fs.StringVar(&f.Constraint, "constraint", f.Constraint, `build constraint expression of the generated files, such as "linux && !appengine", in addition to the constraint of the file declaring the type`)
+	fs.BoolVar(&f.CommandLine, "command-line", f.CommandLine, "record the gooptions command line in the header of the generated files")
+
+	fs.Func("template", `template file defining blocks such as "option" to replace in the default template, or replacing the whole file if it has content outside of its definitions (repeatable)`, func(s string) error {
+		f.TemplateFiles = append(f.TemplateFiles, s)
+		return nil
+	})
+
+	fs.StringVar(&f.Plugin, "plugin", f.Plugin, "plugin writing the files instead of gooptions, the executable gooptions-gen-<plugin> in PATH or a path to an executable, which reads the models as JSON from stdin and writes the files to write as JSON to stdout")
+	fs.StringVar(&f.PluginParam, "plugin-param", f.PluginParam, "parameter passed to the plugin in its request")
+
+	fs.BoolVar(&f.CopyLocks, "copylocks", f.CopyLocks, "generate options for fields containing locks such as sync.Mutex, which go vet reports as copied, or values of standard types that must not be copied such as json.Encoder")
+
+	fs.BoolVar(&f.Tests, "tests", f.Tests, `also look for the type in the package's _test.go files, and write its options to a _test.go file if it is declared in one; such types are reflected on by a test of the package, which runs its TestMain`)
+
+	fs.StringVar(&f.Tags, "tags", f.Tags, "comma separated build tags used to load the package and build the reflection program")
+	fs.StringVar(&f.GoFlags, "goflags", f.GoFlags, "go command flags added to GOFLAGS when loading the package and building the reflection program")
+	fs.StringVar(&f.GoCmd, "go", f.GoCmd, "go command to use, a name in PATH or a path to a toolchain binary")
+	fs.StringVar(&f.GOOS, "goos", f.GOOS, "GOOS to load the package for, which must be able to run on this machine (default: the environment's)")
+	fs.StringVar(&f.GOARCH, "goarch", f.GOARCH, "GOARCH to load the package for, which must be able to run on this machine (default: the environment's)")
 
 	err := fs.Parse(args)
 	if err != nil {
 		fs.Usage()
 		return nil, err
 	}
+	fs.Visit(func(fl *flag.Flag) {
+		f.set[fl.Name] = true
+	})
+	if fs.NArg() > 0 {
+		f.Patterns = fs.Args()
+		f.set["patterns"] = true
+	}
+	if f.Parallel < 1 {
+		f.Parallel = 1
+	}
+	if f.Plugin != "" && f.DestinationPath != "" {
+		return nil, fmt.Errorf("-dest cannot be used with -plugin, which names the files it writes")
+	}
+	if f.DestinationPath == "-" && f.Check {
+		return nil, fmt.Errorf("-check cannot be used with -dest -, which writes no file to check")
+	}
+	if f.DestinationPath == "-" && f.DryRun {
+		return nil, fmt.Errorf("-dry-run cannot be used with -dest -, which writes no file")
+	}
+	if f.Constraint != "" {
+		if _, err := constraint.Parse("//go:build " + f.Constraint); err != nil {
+			return nil, fmt.Errorf("invalid -constraint %q: %v", f.Constraint, err)
+		}
+	}
+
 	return f, nil
 }
 
+// ReadLicense reads LicenseFile, relative to cwd, into License.
+func (f *Flags) ReadLicense(cwd string) error {
+	if f.LicenseFile == "" {
+		return nil
+	}
+	filename := f.LicenseFile
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(cwd, filename)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read license: %v", err)
+	}
+	f.License = string(b)
+	return nil
+}
+
+// ReadTemplates reads TemplateFiles, relative to cwd, into Templates, and
+// returns an error locating the first problem of the templates.
+func (f *Flags) ReadTemplates(cwd string) error {
+	templates := []*model.Template{}
+	for _, name := range f.TemplateFiles {
+		filename := name
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(cwd, filename)
+		}
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %v", err)
+		}
+		templates = append(templates, &model.Template{Name: name, Text: string(b)})
+	}
+	if err := model.ValidateTemplates(templates); err != nil {
+		return err
+	}
+	f.Templates = templates
+	return nil
+}
+
+// CommandLineString returns the gooptions command line the flags were parsed
+// from, with the arguments quoted as needed.
+func (f *Flags) CommandLineString() string {
+	result := []string{"gooptions"}
+	for _, arg := range f.args {
+		result = append(result, quoteFlagValue(arg))
+	}
+	return strings.Join(result, " ")
+}
+
+// TypeNames returns the names and patterns of the types to generate options
+// for, which are empty for the types annotated with GenerateDirective.
+func (f *Flags) TypeNames() []string {
+	if f.All {
+		return []string{"*"}
+	}
+	result := []string{}
+	for _, name := range strings.Split(f.Type, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// CheckTypes returns an error if the flags cannot generate the options of the
+// types of tp.
+func (f *Flags) CheckTypes(tp *TargetPackage) error {
+	if len(tp.Types) > 1 {
+		// Types with an option setting of their own do not take the flag.
+		n := 0
+		for _, tt := range tp.Types {
+			if _, ok := tt.Directive.setting("option"); !ok {
+				n++
+			}
+		}
+		if n > 1 && f.set["option"] && !strings.Contains(f.OptionName, model.TypePlaceholder) {
+			return fmt.Errorf("-option must contain %s to name the option types of %v types", model.TypePlaceholder, n)
+		}
+		if f.DestinationPath != "" && !f.Combined {
+			return fmt.Errorf("-dest requires -combined to write the options of %v types", len(tp.Types))
+		}
+	}
+
+	if f.Combined {
+		first := tp.Types[0]
+		for _, tt := range tp.Types[1:] {
+			if tt.Package.Path != first.Package.Path || tt.Test != first.Test {
+				return fmt.Errorf("-combined cannot write the options of %s and %s to a single file, they are declared in different packages or only one in a test file", first.Name, tt.Name)
+			}
+		}
+	}
+	return nil
+}
+
 func exit(err error, exitCode int) {
 	fmt.Fprintln(os.Stderr, err)
 	os.Exit(exitCode)
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+func TestNewFlags(t *testing.T) {
+	for _, test := range []struct {
+		args    []string
+		wantErr bool
+	}{
+		{args: []string{"-type", "User", "-dest", "-"}},
+		{args: []string{"-type", "User", "-dest", "-", "-check"}, wantErr: true},
+		{args: []string{"-type", "User", "-dest", "-", "-dry-run"}, wantErr: true},
+		{args: []string{"-type", "User", "-dest", "user.go", "-check", "-dry-run"}},
+		{args: []string{"-type", "User", "-dest", "user.go", "-plugin", "json"}, wantErr: true},
+	} {
+		if _, err := NewFlags(test.args); (err != nil) != test.wantErr {
+			t.Errorf("NewFlags(%q) = %v, want error %v", test.args, err, test.wantErr)
+		}
+	}
+}
+
+func TestFlagsCheckTypes(t *testing.T) {
+	pkg := &model.Package{Name: "example", Path: "example.com/example"}
+	tp := &TargetPackage{
+		Package: pkg,
+		Types:   []*TargetType{{Name: "User", Package: pkg}, {Name: "Account", Package: pkg}},
+	}
+
+	for _, test := range []struct {
+		args    []string
+		wantErr bool
+	}{
+		{args: []string{"-type", "User,Account"}},
+		{args: []string{"-type", "User,Account", "-dest", "options.go"}, wantErr: true},
+		{args: []string{"-type", "User,Account", "-dest", "options.go", "-combined"}},
+		{args: []string{"-type", "User,Account", "-option", "Option"}, wantErr: true},
+		{args: []string{"-type", "User,Account", "-option", "{Type}Option"}},
+	} {
+		f, err := NewFlags(test.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.CheckTypes(tp); (err != nil) != test.wantErr {
+			t.Errorf("CheckTypes() with %q = %v, want error %v", test.args, err, test.wantErr)
+		}
+	}
+}
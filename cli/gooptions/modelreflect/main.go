@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/gob"
 	"flag"
 	"fmt"
 	"os"
@@ -14,11 +13,6 @@ var output = flag.String("output", "", "The output file name, or empty to use st
 func main() {
 	flag.Parse()
 
-	structType, err := model.NewStructTypeFromReflectType(ReflectTypeVar)
-	if err != nil {
-		exit(err, 1)
-	}
-
 	outputFile := os.Stdout
 	if *output != "" {
 		var err error
@@ -33,8 +27,8 @@ func main() {
 		}()
 	}
 
-	if err := gob.NewEncoder(outputFile).Encode(structType); err != nil {
-		exit(fmt.Errorf("gob encode error: %v", err), 4)
+	if err := model.EncodeStructTypes(outputFile, ReflectTypeVars); err != nil {
+		exit(fmt.Errorf("encode error: %v", err), 4)
 	}
 }
 
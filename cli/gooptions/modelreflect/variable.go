@@ -4,4 +4,6 @@ import (
 	"reflect"
 )
 
-var ReflectTypeVar = reflect.TypeOf((*struct{})(nil)).Elem()
+var ReflectTypeVars = []reflect.Type{
+	reflect.TypeOf((*struct{})(nil)).Elem(),
+}
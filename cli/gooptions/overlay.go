@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// GeneratedFilesOverlay returns replacements for the files in dirs previously
+// generated by gooptions, keyed by file path. The generated files refer to the
+// fields of their struct types, so the package stops compiling when a field is
+// removed, and the reflection program could not be built to regenerate them.
+// The replacements keep every declaration, which hand-written code may use,
+// but drop the function bodies.
+func GeneratedFilesOverlay(dirs ...string) (map[string][]byte, error) {
+	filenames := []string{}
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		filenames = append(filenames, matches...)
+	}
+
+	result := map[string][]byte{}
+	for _, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if !model.IsGeneratedFile(src) {
+			continue
+		}
+
+		stub, err := StubGeneratedFile(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		result[filename] = stub
+	}
+
+	return result, nil
+}
+
+// StubGeneratedFile returns src with the bodies of its functions replaced by a
+// panic. Imports only used in the bodies are changed to blank imports. If src
+// does not parse, only its package clause is kept.
+func StubGeneratedFile(filename string, src []byte) ([]byte, error) {
+	// Comments are kept for the //go:build line, which excludes the stub from
+	// builds the generated file is excluded from.
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		if file == nil || file.Name == nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf("package %s\n", file.Name.Name)), nil
+	}
+
+	usedBefore := usedQualifiers(file)
+
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		fd.Body = &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ExprStmt{
+					X: &ast.CallExpr{
+						Fun:  ast.NewIdent("panic"),
+						Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: `"gooptions: stub"`}},
+					},
+				},
+			},
+		}
+	}
+
+	usedAfter := usedQualifiers(file)
+	for _, spec := range file.Imports {
+		name := importName(spec)
+		if usedBefore[name] && !usedAfter[name] {
+			spec.Name = ast.NewIdent("_")
+		}
+	}
+
+	b := &bytes.Buffer{}
+	if err := printer.Fprint(b, fset, file); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// usedQualifiers returns the identifiers used as the operand of a selector,
+// which include every package qualifier.
+func usedQualifiers(file *ast.File) map[string]bool {
+	result := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if se, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := se.X.(*ast.Ident); ok {
+				result[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// importName returns the name spec is referred to by, guessing it from the
+// import path for imports without an explicit name.
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+
+	importPath, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return ""
+	}
+	return model.GuessPackageName(importPath)
+}
+
+// WriteOverlayFile writes overlay to dir in the format of the go command's
+// -overlay flag and returns the name of the file to pass to it.
+func WriteOverlayFile(dir string, overlay map[string][]byte) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	replace := map[string]string{}
+	i := 0
+	for filename, contents := range overlay {
+		replacement := filepath.Join(dir, fmt.Sprintf("overlay%d.go.txt", i))
+		i++
+		if err := ioutil.WriteFile(replacement, contents, 0666); err != nil {
+			return "", err
+		}
+		replace[filename] = replacement
+	}
+
+	b, err := json.Marshal(map[string]map[string]string{
+		"Replace": replace,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err := ioutil.WriteFile(overlayFile, b, 0666); err != nil {
+		return "", err
+	}
+	return overlayFile, nil
+}
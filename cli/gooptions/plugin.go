@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// PluginCommand returns the command of the plugin name, which is the
+// executable model.PluginPrefix+name in PATH, or name itself if it is a path.
+func PluginCommand(name string) (*exec.Cmd, error) {
+	if strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, err
+		}
+		return exec.Command(abs), nil
+	}
+
+	executable, err := exec.LookPath(model.PluginPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(executable), nil
+}
+
+// RunPlugin pipes the files gooptions would write to dir, named by names, to
+// the plugin name, and returns the files it returns to write instead.
+func RunPlugin(name, parameter, dir string, files []*model.File, names []string) ([]*GeneratedSource, error) {
+	req := &model.PluginRequest{
+		Parameter: parameter,
+		Dir:       dir,
+	}
+	for i, file := range files {
+		path := file.Models[0].Options.OutputPath(names[i], dir, "")
+		req.Files = append(req.Files, &model.PluginFile{Name: filepath.Base(path), File: file})
+	}
+	in := &bytes.Buffer{}
+	if err := model.WritePluginRequest(in, req); err != nil {
+		return nil, err
+	}
+
+	cmd, err := PluginCommand(name)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", name, err)
+	}
+	out := &bytes.Buffer{}
+	cmd.Dir = dir
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", name, err)
+	}
+
+	resp, err := model.ReadPluginResponse(out)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %v", name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", name, resp.Error)
+	}
+
+	result := []*GeneratedSource{}
+	for _, file := range resp.Files {
+		result = append(result, &GeneratedSource{
+			Path: filepath.Join(dir, filepath.FromSlash(file.Name)),
+			Src:  []byte(file.Content),
+		})
+	}
+	return result, nil
+}
@@ -3,41 +3,485 @@ package main
 import (
 	"bytes"
 	_ "embed"
-	"encoding/gob"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/token"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"text/template"
 
 	"github.com/shipyardapp/gooptions/model"
-	"golang.org/x/tools/go/packages"
 )
 
-func NewModelPackageReflect(cwd, pattern string) (*model.Package, error) {
-	packages, err := packages.Load(
-		&packages.Config{
-			Mode:  packages.NeedName,
-			Tests: false,
-			Dir:   cwd,
-		},
-		pattern,
-	)
+// TargetPackage is the package declaring the struct types to generate options
+// for.
+type TargetPackage struct {
+	*model.Package
+
+	// Dir is the directory of the package's files.
+	Dir string
+
+	// Types to generate options for, in the order they were requested.
+	Types []*TargetType
+}
+
+// TargetType is a struct type to generate options for.
+type TargetType struct {
+	Name string
+
+	// Package is the package declaring the type, which is the external test
+	// package for types declared in its files.
+	Package *model.Package
+
+	// Test reports whether the type is declared in a _test.go file, either of
+	// the package itself or of its external test package.
+	Test bool
+
+	// BuildConstraint is the expression of the //go:build line of the file
+	// declaring the type, or empty if it has none.
+	BuildConstraint string
+
+	// Directive is the GenerateDirective of the type, or nil if it has none.
+	Directive *Directive
+
+	// FieldDocs are the doc comments of the fields declared by the struct,
+	// keyed by field name.
+	FieldDocs map[string]string
+}
+
+// TypeNames returns the names of the types of tp.
+func (tp *TargetPackage) TypeNames() []string {
+	result := []string{}
+	for _, tt := range tp.Types {
+		result = append(result, tt.Name)
+	}
+	return result
+}
+
+// InPackage reports whether the reflection program has to be compiled into the
+// package, because package main, test files and unexported types cannot be
+// imported.
+func (tp *TargetPackage) InPackage() bool {
+	if tp.Name == "main" {
+		return true
+	}
+	for _, tt := range tp.Types {
+		if tt.Test || !token.IsExported(tt.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTargetPackages loads the packages matching patterns and finds the types
+// named by typeNames in each of them. typeNames are either type names or
+// path.Match patterns matching the names of non-generic struct types. Without
+// typeNames the types annotated with GenerateDirective are found. If a
+// single package matches, every one of typeNames must be found in it. Otherwise
+// packages without any of the types are left out.
+func LoadTargetPackages(bc *BuildConfig, cwd string, patterns []string, typeNames []string, tests bool) ([]*TargetPackage, error) {
+	fset := token.NewFileSet()
+	loaded, err := loadPackages(bc, cwd, fset, tests, patterns...)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(packages) != 1 {
-		return nil, fmt.Errorf("wrong number of packages found %v %v", len(packages), packages)
+	// With tests each package is loaded along with its test variant, its
+	// external test package and the generated test main package.
+	groups := map[string][]*loadedPackage{}
+	paths := []string{}
+	for _, p := range loaded {
+		if strings.HasSuffix(p.PkgPath, ".test") || len(p.Syntax) == 0 {
+			continue
+		}
+		pkgPath := strings.TrimSuffix(p.PkgPath, "_test")
+		if _, ok := groups[pkgPath]; !ok {
+			paths = append(paths, pkgPath)
+		}
+		groups[pkgPath] = append(groups[pkgPath], p)
+	}
+	sort.Strings(paths)
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no packages found matching %v", strings.Join(patterns, " "))
 	}
 
-	p := packages[0]
-	return &model.Package{
-		Name: p.Name,
-		Path: p.PkgPath,
-	}, nil
+	result := []*TargetPackage{}
+	for _, pkgPath := range paths {
+		tp, err := newTargetPackage(fset, groups[pkgPath], typeNames, tests, len(paths) == 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(tp.Types) > 0 {
+			result = append(result, tp)
+		}
+	}
+
+	if len(result) == 0 {
+		if len(typeNames) == 0 {
+			return nil, fmt.Errorf("no types annotated with %s found in packages matching %v", GenerateDirective, strings.Join(patterns, " "))
+		}
+		return nil, fmt.Errorf("no types %v found in packages matching %v", strings.Join(typeNames, ","), strings.Join(patterns, " "))
+	}
+	return result, nil
+}
+
+// newTargetPackage finds the types named by typeNames in the package ps, which
+// holds the variants of the same package. If required is set, every one of
+// typeNames must be found.
+func newTargetPackage(fset *token.FileSet, ps []*loadedPackage, typeNames []string, tests bool, required bool) (*TargetPackage, error) {
+	// Prefer the package without its test files, then the test variant and
+	// then the external test package.
+	sort.SliceStable(ps, func(i, j int) bool {
+		return packageOrder(ps[i]) < packageOrder(ps[j])
+	})
+
+	tp := &TargetPackage{
+		Package: &model.Package{
+			Name: ps[0].Name,
+			Path: ps[0].PkgPath,
+		},
+	}
+
+	// Types of the package's own files are found in both the package and its
+	// test variant, the first one is kept.
+	seen := map[string]bool{}
+	add := func(p *loadedPackage, file *ast.File, spec typeSpec) error {
+		key := p.PkgPath + "." + spec.Name.Name
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+
+		directive, err := findDirective(fset, spec.decl, spec.TypeSpec)
+		if err != nil {
+			return err
+		}
+
+		filename := fset.File(file.Pos()).Name()
+		tp.Dir = filepath.Dir(filename)
+		tp.Types = append(tp.Types, &TargetType{
+			Name: spec.Name.Name,
+			Package: &model.Package{
+				Name: p.Name,
+				Path: p.PkgPath,
+			},
+			Test:            strings.HasSuffix(filename, "_test.go"),
+			BuildConstraint: buildConstraint(filename, file),
+			Directive:       directive,
+			FieldDocs:       fieldDocs(spec.TypeSpec),
+		})
+		return nil
+	}
+
+	// Without type names the types annotated with GenerateDirective are
+	// generated.
+	if len(typeNames) == 0 {
+		for _, p := range ps {
+			for _, file := range p.Syntax {
+				for _, spec := range typeSpecs(file) {
+					directive, err := findDirective(fset, spec.decl, spec.TypeSpec)
+					if err != nil {
+						return nil, err
+					}
+					if directive == nil {
+						continue
+					}
+					if !isStructTypeSpec(spec.TypeSpec) {
+						return nil, fmt.Errorf("%v: %s is only supported on non-generic struct types", fset.Position(spec.Pos()), GenerateDirective)
+					}
+					if err := add(p, file, spec); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		if len(tp.Types) == 0 && required {
+			return nil, fmt.Errorf("no types annotated with %s found in package %v (use -type or -all to select types)", GenerateDirective, ps[0].PkgPath)
+		}
+		return tp, nil
+	}
+
+	for _, typeName := range typeNames {
+		if !isTypePattern(typeName) {
+			p, file, spec := findTypeSpec(ps, typeName)
+			if file == nil {
+				if !required {
+					continue
+				}
+				if !tests {
+					return nil, fmt.Errorf("type %s not found in package %v (use -tests for types declared in _test.go files)", typeName, ps[0].PkgPath)
+				}
+				return nil, fmt.Errorf("type %s not found in package %v", typeName, ps[0].PkgPath)
+			}
+			if err := add(p, file, spec); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, err := path.Match(typeName, ""); err != nil {
+			return nil, fmt.Errorf("bad type pattern %q: %v", typeName, err)
+		}
+		found := false
+		for _, p := range ps {
+			for _, file := range p.Syntax {
+				for _, spec := range typeSpecs(file) {
+					if !isStructTypeSpec(spec.TypeSpec) {
+						continue
+					}
+					if ok, _ := path.Match(typeName, spec.Name.Name); ok {
+						found = true
+						if err := add(p, file, spec); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+		}
+		if !found && required {
+			return nil, fmt.Errorf("no struct types matching %q found in package %v", typeName, ps[0].PkgPath)
+		}
+	}
+
+	return tp, nil
+}
+
+func packageOrder(p *loadedPackage) int {
+	switch {
+	case strings.HasSuffix(p.PkgPath, "_test"):
+		return 2
+	case p.ID != p.PkgPath:
+		return 1
+	}
+	return 0
+}
+
+// isTypePattern reports whether typeName is a pattern rather than a name.
+func isTypePattern(typeName string) bool {
+	return strings.ContainsAny(typeName, `*?[\`)
+}
+
+// findTypeSpec returns the first of ps declaring the package level type
+// typeName along with the declaring file, or a nil file if none of them do.
+func findTypeSpec(ps []*loadedPackage, typeName string) (*loadedPackage, *ast.File, typeSpec) {
+	for _, p := range ps {
+		for _, file := range p.Syntax {
+			for _, spec := range typeSpecs(file) {
+				if spec.Name.Name == typeName {
+					return p, file, spec
+				}
+			}
+		}
+	}
+	return nil, nil, typeSpec{}
+}
+
+// typeSpec is a package level type declaration.
+type typeSpec struct {
+	*ast.TypeSpec
+
+	// decl is the declaration holding the TypeSpec, which has its doc comment
+	// unless it is grouped with others.
+	decl *ast.GenDecl
+}
+
+// typeSpecs returns the package level type declarations of file.
+func typeSpecs(file *ast.File) []typeSpec {
+	result := []typeSpec{}
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			result = append(result, typeSpec{TypeSpec: spec.(*ast.TypeSpec), decl: gd})
+		}
+	}
+	return result
+}
+
+// isStructTypeSpec reports whether spec declares a non-generic struct type,
+// which options can be generated for. Aliases are left out so a type is not
+// matched under two names.
+func isStructTypeSpec(spec *ast.TypeSpec) bool {
+	if spec.Assign.IsValid() || spec.TypeParams != nil {
+		return false
+	}
+	_, ok := spec.Type.(*ast.StructType)
+	return ok
+}
+
+// fieldDocs returns the doc comments of the fields of the struct type of spec,
+// or else their line comments, keyed by field name.
+func fieldDocs(spec *ast.TypeSpec) map[string]string {
+	result := map[string]string{}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return result
+	}
+	for _, field := range st.Fields.List {
+		doc := field.Doc.Text()
+		if doc == "" {
+			doc = field.Comment.Text()
+		}
+		if doc == "" {
+			continue
+		}
+		for _, name := range field.Names {
+			result[name.Name] = doc
+		}
+		if len(field.Names) == 0 {
+			result[embeddedFieldName(field.Type)] = doc
+		}
+	}
+	return result
+}
+
+// embeddedFieldName returns the name of the embedded field of type expr, which
+// is the name of the type without its package and type arguments.
+func embeddedFieldName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.StarExpr:
+		return embeddedFieldName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedFieldName(expr.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldName(expr.X)
+	}
+	return ""
+}
+
+// buildConstraint returns the build constraint of the file filename, which is
+// the expression of its //go:build line and the constraint implied by its
+// _GOOS and _GOARCH suffixes, or "" if it has none. The generated file is named
+// after the type, so it carries the whole constraint in its //go:build line.
+func buildConstraint(filename string, file *ast.File) string {
+	expr := ""
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if constraint.IsGoBuild(c.Text) {
+				expr = strings.TrimSpace(strings.TrimPrefix(c.Text, "//go:build"))
+			}
+		}
+	}
+	return model.AndConstraints(filenameConstraint(filename), expr)
+}
+
+// filenameConstraint returns the constraint implied by the _GOOS, _GOARCH or
+// _GOOS_GOARCH suffix of filename, before _test, such as "linux && amd64" for
+// "config_linux_amd64.go", or "" if it has none.
+func filenameConstraint(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return ""
+	}
+
+	// As for go/build, the part before the first underscore is not a suffix.
+	elements := strings.Split(name[i+1:], "_")
+	n := len(elements)
+	if n >= 2 && isFilenameGOOS(elements[n-2]) && isFilenameGOARCH(elements[n-1]) {
+		return elements[n-2] + " && " + elements[n-1]
+	}
+	if isFilenameGOOS(elements[n-1]) || isFilenameGOARCH(elements[n-1]) {
+		return elements[n-1]
+	}
+	return ""
+}
+
+// isFilenameGOOS reports whether go/build treats the filename suffix _s as a
+// GOOS. Files with an unknown suffix match every context, while files with a
+// GOOS suffix only match the contexts of that GOOS.
+func isFilenameGOOS(s string) bool {
+	filename := "x_" + s + ".go"
+	return !matchFilename("gooptionsnone", "gooptionsnone", filename) &&
+		matchFilename(s, "gooptionsnone", filename)
+}
+
+// isFilenameGOARCH reports whether go/build treats the filename suffix _s as a
+// GOARCH.
+func isFilenameGOARCH(s string) bool {
+	filename := "x_" + s + ".go"
+	return !matchFilename("gooptionsnone", "gooptionsnone", filename) &&
+		matchFilename("gooptionsnone", s, filename)
+}
+
+// matchFilename reports whether go/build includes the file filename, whose
+// content has no constraints, in the build for goos and goarch.
+func matchFilename(goos, goarch, filename string) bool {
+	ctxt := build.Default
+	ctxt.GOOS = goos
+	ctxt.GOARCH = goarch
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("package p\n")), nil
+	}
+	ok, err := ctxt.MatchFile("", filename)
+	return err == nil && ok
+}
+
+// ResolvePackageNames sets the name of every package referenced by sts, the
+// models of the types of tp, to the name declared in the package's source. The
+// names derived from import paths by the reflection program are wrong for paths
+// such as "gopkg.in/yaml.v3". The names of tp and of the packages declaring its
+// types are known, and go list cannot find external test packages.
+func ResolvePackageNames(bc *BuildConfig, tp *TargetPackage, sts []*model.StructType) error {
+	names := map[string]string{tp.Path: tp.Name}
+	for _, tt := range tp.Types {
+		names[tt.Package.Path] = tt.Package.Name
+	}
+
+	ps := []*model.Package{}
+	for _, st := range sts {
+		ps = append(ps, st.Packages()...)
+	}
+
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, p := range ps {
+		if _, ok := names[p.Path]; !ok && !seen[p.Path] {
+			seen[p.Path] = true
+			paths = append(paths, p.Path)
+		}
+	}
+
+	if len(paths) > 0 {
+		loaded, err := loadPackages(bc, tp.Dir, nil, false, paths...)
+		if err != nil {
+			return err
+		}
+		for _, p := range loaded {
+			if p.Name != "" {
+				names[p.PkgPath] = p.Name
+			}
+		}
+	}
+
+	for _, p := range ps {
+		name, ok := names[p.Path]
+		if !ok {
+			return fmt.Errorf("failed to find name of package %q", p.Path)
+		}
+		p.Name = name
+	}
+
+	return nil
 }
 
 //go:embed modelreflect/main.go
@@ -46,15 +490,32 @@ var ModelReflectMainGo []byte
 //go:embed modelreflect/variable.gotemplate
 var ModelReflectVariableGoTemplate string
 
-func BuildRunReflectProgram(mp *model.Package, typeName string) (*model.StructType, error) {
+//go:embed modelreflect/test.gotemplate
+var ModelReflectTestGoTemplate string
+
+// BuildRunReflectProgram builds and runs a single reflection program for all
+// the types of tp and returns their models in the same order. overlay replaces
+// previously generated files, see GeneratedFilesOverlay.
+func BuildRunReflectProgram(bc *BuildConfig, tp *TargetPackage, overlay map[string][]byte) ([]*model.StructType, error) {
+	w, err := NewReflectWorkspace(bc, tp)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Remove()
+
+	if tp.InPackage() {
+		return BuildRunInPackage(w, tp, overlay)
+	}
+
+	mp := tp.Package
 	t, err := template.New("modelreflect").Parse(ModelReflectVariableGoTemplate)
 	if err != nil {
 		return nil, err
 	}
 
-	templateData := map[string]string{
-		"Path":     mp.Path,
-		"TypeName": typeName,
+	templateData := map[string]interface{}{
+		"Path":      mp.Path,
+		"TypeNames": tp.TypeNames(),
 	}
 
 	variableGo := &bytes.Buffer{}
@@ -63,27 +524,24 @@ func BuildRunReflectProgram(mp *model.Package, typeName string) (*model.StructTy
 	}
 	variableGoBytes := variableGo.Bytes()
 
-	// Attempt to run in the current working directory.
-	return BuildRunInDirectory(variableGoBytes, ".")
+	return BuildRunInWorkspace(w, variableGoBytes, overlay)
 }
 
-func BuildRunInDirectory(variableGo []byte, dir string) (*model.StructType, error) {
-	mainDir, err := GenerateModelReflectMainDirectory(variableGo, dir)
-	if err != nil {
+// BuildRunInWorkspace builds the reflection program importing the target
+// package in the module of the workspace w and runs it.
+func BuildRunInWorkspace(w *ReflectWorkspace, variableGo []byte, overlay map[string][]byte) ([]*model.StructType, error) {
+	if err := WriteModelReflectProgram(w.ProgramDir, variableGo); err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err := os.RemoveAll(mainDir); err != nil {
-			log.Printf("failed to remove temp directory: %v", mainDir)
-		}
-	}()
 
-	programName := "modelreflect.bin"
-	programBinary := filepath.Join(mainDir, programName)
+	programBinary := filepath.Join(w.Dir, "modelreflect.bin")
 
-	cmdArgs := []string{"build", "-o", programName, "."}
-	cmd := exec.Command("go", cmdArgs...)
-	cmd.Dir = mainDir
+	overlayFile, err := WriteOverlayFile(w.Dir, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := w.BuildCommand(w.ProgramDir, "build", "-overlay", overlayFile, "-o", programBinary, ".")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -93,32 +551,116 @@ func BuildRunInDirectory(variableGo []byte, dir string) (*model.StructType, erro
 	return RunProgram(programBinary)
 }
 
-func GenerateModelReflectMainDirectory(variableGo []byte, dir string) (string, error) {
-	tempDir, err := ioutil.TempDir(dir, "modelreflect")
+// reflectTest is a test added to the package by BuildRunInPackage, reflecting
+// on the types of one of the package and its external test package.
+type reflectTest struct {
+	PackageName string
+	TestName    string
+	Output      string
+	TypeNames   []string
+
+	filename string
+
+	// indexes of the types in TargetPackage.Types.
+	indexes []int
+}
+
+// BuildRunInPackage runs the reflection program as a test of the package tp,
+// which can reflect on types of package main and of test files. The test files
+// are added to the package by an overlay, so the package directory is left
+// untouched. Like any test, it runs the init functions of the package and its
+// TestMain, which must call m.Run for the reflection test to run.
+func BuildRunInPackage(w *ReflectWorkspace, tp *TargetPackage, overlay map[string][]byte) ([]*model.StructType, error) {
+	t, err := template.New("modelreflect").Parse(ModelReflectTestGoTemplate)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// Types of the external test package are reflected on by a test of its
+	// own, all the others by a test of the package.
+	tests := []*reflectTest{}
+	byPackage := map[string]*reflectTest{}
+	for i, tt := range tp.Types {
+		rt, ok := byPackage[tt.Package.Path]
+		if !ok {
+			rt = &reflectTest{
+				PackageName: tt.Package.Name,
+				TestName:    "TestGooptionsModelReflect",
+				Output:      filepath.Join(w.Dir, "model.out"),
+				filename:    "gooptions_modelreflect_test.go",
+			}
+			if tt.Package.Path != tp.Path {
+				rt.TestName = "TestGooptionsModelReflectExternal"
+				rt.Output = filepath.Join(w.Dir, "model_external.out")
+				rt.filename = "gooptions_modelreflect_external_test.go"
+			}
+			byPackage[tt.Package.Path] = rt
+			tests = append(tests, rt)
+		}
+		rt.TypeNames = append(rt.TypeNames, tt.Name)
+		rt.indexes = append(rt.indexes, i)
 	}
 
+	testOverlay := map[string][]byte{}
+	for _, rt := range tests {
+		testGo := &bytes.Buffer{}
+		if err := t.Execute(testGo, rt); err != nil {
+			return nil, err
+		}
+		testOverlay[filepath.Join(tp.Dir, rt.filename)] = testGo.Bytes()
+	}
+	for filename, contents := range overlay {
+		testOverlay[filename] = contents
+	}
+	overlayFile, err := WriteOverlayFile(w.Dir, testOverlay)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := w.BuildCommand(tp.Dir, "test", "-overlay", overlayFile, "-count", "1", "-run", "^TestGooptionsModelReflect", ".")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Stderr.Write(output)
+		return nil, fmt.Errorf("the reflection test of %v failed, which also runs its init functions and TestMain: %v", tp.Path, err)
+	}
+
+	result := make([]*model.StructType, len(tp.Types))
+	for _, rt := range tests {
+		if _, err := os.Stat(rt.Output); os.IsNotExist(err) {
+			return nil, fmt.Errorf("the reflection test %v of %v did not run, check that its TestMain calls m.Run", rt.TestName, tp.Path)
+		}
+		sts, err := decodeStructTypesFile(rt.Output)
+		if err != nil {
+			return nil, err
+		}
+		if len(sts) != len(rt.indexes) {
+			return nil, fmt.Errorf("wrong number of models in %v: %v, want %v", rt.Output, len(sts), len(rt.indexes))
+		}
+		for i, st := range sts {
+			result[rt.indexes[i]] = st
+		}
+	}
+	return result, nil
+}
+
+// WriteModelReflectProgram writes the files of the reflection program to the
+// directory of its module.
+func WriteModelReflectProgram(dir string, variableGo []byte) error {
 	if err := ioutil.WriteFile(
-		filepath.Join(tempDir, "variable.go"),
+		filepath.Join(dir, "variable.go"),
 		variableGo,
 		0666,
 	); err != nil {
-		return "", os.RemoveAll(tempDir)
+		return err
 	}
 
-	if err := ioutil.WriteFile(
-		filepath.Join(tempDir, "main.go"),
+	return ioutil.WriteFile(
+		filepath.Join(dir, "main.go"),
 		ModelReflectMainGo,
 		0666,
-	); err != nil {
-		return "", os.RemoveAll(tempDir)
-	}
-
-	return tempDir, nil
+	)
 }
 
-func RunProgram(programBinary string) (*model.StructType, error) {
+func RunProgram(programBinary string) ([]*model.StructType, error) {
 	outputFile, err := ioutil.TempFile("", "modelreflectoutput")
 	if err != nil {
 		return nil, err
@@ -136,19 +678,24 @@ func RunProgram(programBinary string) (*model.StructType, error) {
 		return nil, err
 	}
 
-	f, err := os.Open(outputFile.Name())
+	return decodeStructTypesFile(outputFile.Name())
+}
+
+func decodeStructTypesFile(filename string) ([]*model.StructType, error) {
+	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	var modelStructType model.StructType
-	if err := gob.NewDecoder(f).Decode(&modelStructType); err != nil {
-		return nil, err
+	sts, err := model.DecodeStructTypes(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read the models of the reflection program, which may be built with the model package of another version of gooptions: %v", err)
 	}
 
 	if err := f.Close(); err != nil {
 		return nil, err
 	}
 
-	return &modelStructType, nil
+	return sts, nil
 }
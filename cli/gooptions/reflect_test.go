@@ -0,0 +1,113 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildConstraint(t *testing.T) {
+	tests := []struct {
+		filename string
+		src      string
+		want     string
+	}{
+		{"config.go", "package p\n", ""},
+		{"config_linux.go", "package p\n", "linux"},
+		{"config_arm64.go", "package p\n", "arm64"},
+		{"config_linux_amd64.go", "package p\n", "linux && amd64"},
+		{"config_windows_test.go", "package p\n", "windows"},
+		{"linux.go", "package p\n", ""},
+		{"server_config.go", "package p\n", ""},
+		{"config_linux.go", "//go:build !appengine\n\npackage p\n", "(linux) && (!appengine)"},
+		{"config.go", "//go:build integration\n\npackage p\n", "integration"},
+	}
+	for _, test := range tests {
+		file, err := parser.ParseFile(token.NewFileSet(), test.filename, test.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buildConstraint("/src/"+test.filename, file); got != test.want {
+			t.Errorf("buildConstraint(%q) = %q, want %q", test.filename, got, test.want)
+		}
+	}
+}
+
+func TestBuildRunReflectProgramTestMain(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"example.go": "package example\n",
+		"example_test.go": `package example
+
+import (
+	"os"
+	"testing"
+)
+
+type fixture struct {
+	Name string
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(0)
+}
+`,
+	})
+	bc, err := NewBuildConfig("", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tps, err := LoadTargetPackages(bc, dir, []string{"."}, []string{"fixture"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = BuildRunReflectProgram(bc, tps[0], nil)
+	if err == nil || !strings.Contains(err.Error(), "TestMain calls m.Run") {
+		t.Errorf("BuildRunReflectProgram() = %v, want an error about TestMain", err)
+	}
+}
+
+func TestResolvePackageNamesExternalTest(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"example.go": "package example\n\ntype User struct {\n\tName string\n}\n",
+		"example_test.go": `package example_test
+
+import "example.com/example"
+
+type Config struct {
+	Debug bool
+}
+
+type fixture struct {
+	Config Config
+	User   example.User
+}
+`,
+	})
+	bc, err := NewBuildConfig("", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tps, err := LoadTargetPackages(bc, dir, []string{"."}, []string{"fixture"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sts, err := BuildRunReflectProgram(bc, tps[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResolvePackageNames(bc, tps[0], sts); err != nil {
+		t.Fatalf("ResolvePackageNames() = %v", err)
+	}
+	names := map[string]string{}
+	for _, p := range sts[0].Packages() {
+		names[p.Path] = p.Name
+	}
+	want := map[string]string{"example.com/example_test": "example_test", "example.com/example": "example"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ResolvePackageNames() set the names %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// GeneratedSource is a file about to be written.
+type GeneratedSource struct {
+	Path string
+	Src  []byte
+
+	// File is the model the source is generated from, or nil for the files of
+	// plugins.
+	File *model.File
+}
+
+// TypeCheck type-checks the sources srcs with the other files of the package
+// they are written to, and returns an error listing the type errors in srcs.
+// The errors in the option functions are reported with the field they are
+// generated for. overlay replaces previously generated files, see
+// GeneratedFilesOverlay, while the dependencies of the package are compiled.
+func TypeCheck(bc *BuildConfig, tp *TargetPackage, overlay map[string][]byte, srcs []*GeneratedSource) error {
+	listed, err := listExports(bc, tp, overlay)
+	if err != nil {
+		return fmt.Errorf("failed to list the dependencies of %v: %v", tp.Path, err)
+	}
+
+	var target *listedExport
+	for _, p := range listed {
+		if p.ImportPath == tp.Path {
+			target = p
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("failed to list %v", tp.Path)
+	}
+
+	// The sources are checked with the variant of the package they belong
+	// to: the package, its test variant or its external test package.
+	variants := map[string][]*GeneratedSource{}
+	for _, src := range srcs {
+		variant := ""
+		if strings.HasSuffix(src.Path, "_test.go") {
+			variant = "test"
+			if strings.HasSuffix(packageName(src), "_test") {
+				variant = "xtest"
+			}
+		}
+		variants[variant] = append(variants[variant], src)
+	}
+
+	messages := []string{}
+	for _, variant := range []string{"", "test", "xtest"} {
+		if len(variants[variant]) == 0 {
+			continue
+		}
+
+		pkgPath := tp.Path
+		filenames := []string{}
+		switch variant {
+		case "":
+			filenames = append(filenames, target.GoFiles...)
+			filenames = append(filenames, target.CgoFiles...)
+		case "test":
+			filenames = append(filenames, target.GoFiles...)
+			filenames = append(filenames, target.CgoFiles...)
+			filenames = append(filenames, target.TestGoFiles...)
+		case "xtest":
+			pkgPath += "_test"
+			filenames = append(filenames, target.XTestGoFiles...)
+		}
+
+		errs, err := typeCheckFiles(listed, tp, pkgPath, variant != "", target.Dir, filenames, variants[variant])
+		if err != nil {
+			return err
+		}
+		messages = append(messages, errs...)
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("generated code does not compile:\n%s", strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+// listedExport is a package as printed by go list -export -json.
+type listedExport struct {
+	ImportPath   string
+	ForTest      string
+	Dir          string
+	Export       string
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+}
+
+// listExports lists the package tp, its test variants and their dependencies
+// with their export data, which compiles the dependencies.
+func listExports(bc *BuildConfig, tp *TargetPackage, overlay map[string][]byte) ([]*listedExport, error) {
+	dir, err := ioutil.TempDir("", "gooptionstypecheck")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	overlayFile, err := WriteOverlayFile(dir, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	// With -e the dependencies are listed even if the package does not
+	// compile.
+	cmd := bc.BuildCommand(tp.Dir, "list", "-e", "-export", "-deps", "-test", "-json", "-overlay", overlayFile, ".")
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*listedExport{}
+	d := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var p listedExport
+		if err := d.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		result = append(result, &p)
+	}
+	return result, nil
+}
+
+// typeCheckFiles type-checks the files filenames of dir as the package pkgPath,
+// with srcs replacing or added to them, and returns the errors in srcs. The
+// imports are resolved to the test variants of packages when test is set.
+func typeCheckFiles(listed []*listedExport, tp *TargetPackage, pkgPath string, test bool, dir string, filenames []string, srcs []*GeneratedSource) ([]string, error) {
+	exports := map[string]string{}
+	for _, p := range listed {
+		importPath := p.ImportPath
+		if i := strings.Index(importPath, " ["); i >= 0 {
+			if !test || p.ForTest != tp.Path {
+				continue
+			}
+			importPath = importPath[:i]
+		} else if _, ok := exports[importPath]; ok {
+			continue
+		}
+		exports[importPath] = p.Export
+	}
+
+	bySrcPath := map[string]*GeneratedSource{}
+	for _, src := range srcs {
+		bySrcPath[src.Path] = src
+	}
+
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	parsed := map[string]*ast.File{}
+	paths := []string{}
+	for _, filename := range filenames {
+		paths = append(paths, filepath.Join(dir, filename))
+	}
+	for _, src := range srcs {
+		if !containsString(paths, src.Path) {
+			paths = append(paths, src.Path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		var src interface{}
+		if generated, ok := bySrcPath[path]; ok {
+			src = generated.Src
+		}
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		parsed[path] = file
+	}
+
+	messages := []string{}
+	config := &types.Config{
+		Importer: importer.ForCompiler(fset, "gc", func(importPath string) (io.ReadCloser, error) {
+			export, ok := exports[importPath]
+			if !ok || export == "" {
+				return nil, fmt.Errorf("no export data for %v", importPath)
+			}
+			return os.Open(export)
+		}),
+		FakeImportC: true,
+		Error: func(err error) {
+			te, ok := err.(types.Error)
+			if !ok {
+				return
+			}
+			position := fset.Position(te.Pos)
+			src, ok := bySrcPath[position.Filename]
+			if !ok {
+				return
+			}
+			messages = append(messages, typeErrorMessage(src, parsed[src.Path], position, te))
+		},
+	}
+	// The errors are collected by config.Error.
+	config.Check(pkgPath, fset, files, nil)
+
+	return messages, nil
+}
+
+// typeErrorMessage returns the message of the type error te in src, prefixed
+// by the type and field the option function holding it is generated for.
+func typeErrorMessage(src *GeneratedSource, file *ast.File, position token.Position, te types.Error) string {
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || te.Pos < fd.Pos() || te.Pos >= fd.End() {
+			continue
+		}
+		if src.File == nil {
+			break
+		}
+		m, field := src.File.FuncField(fd.Name.Name)
+		if field == nil {
+			break
+		}
+		return fmt.Sprintf("%v: field %s.%s (option %s): %s", position, m.StructType.Name, field.Selector, field.FuncName, te.Msg)
+	}
+	return fmt.Sprintf("%v: %s", position, te.Msg)
+}
+
+// packageName returns the name of the package of src.
+func packageName(src *GeneratedSource) string {
+	if src.File != nil {
+		return src.File.Package.Name
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), src.Path, src.Src, parser.PackageClauseOnly)
+	if err != nil {
+		return ""
+	}
+	return file.Name.Name
+}
+
+func containsString(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
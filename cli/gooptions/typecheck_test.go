@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+func TestTypeCheck(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"user.go": "package example\n\ntype User struct {\n\tName string\n}\n",
+	})
+	bc, err := NewBuildConfig("", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := loadTestPackage(t, bc, dir, "User")
+
+	for _, test := range []struct {
+		name    string
+		file    string
+		pkg     string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			file: "user_options.go",
+			pkg:  "example",
+			src:  "package example\n\nfunc WithName(name string) func(*User) {\n\treturn func(u *User) { u.Name = name }\n}\n",
+		},
+		{
+			name:    "missing field",
+			file:    "user_options.go",
+			pkg:     "example",
+			src:     "package example\n\nfunc WithAge(age int) func(*User) {\n\treturn func(u *User) { u.Age = age }\n}\n",
+			wantErr: "user_options.go:4:",
+		},
+		{
+			name:    "test file",
+			file:    "user_options_test.go",
+			pkg:     "example",
+			src:     "package example\n\nvar _ = User{Name: 1}\n",
+			wantErr: "user_options_test.go:3:",
+		},
+		{
+			name: "external test file",
+			file: "user_options_test.go",
+			pkg:  "example_test",
+			src:  "package example_test\n\nimport \"example.com/example\"\n\nvar _ = example.User{Name: \"name\"}\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			src := &GeneratedSource{
+				Path: filepath.Join(dir, test.file),
+				Src:  []byte(test.src),
+				File: &model.File{Package: &model.Package{Path: "example.com/example", Name: test.pkg}},
+			}
+			err := TypeCheck(bc, tp, nil, []*GeneratedSource{src})
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("TypeCheck() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("TypeCheck() = %v, want an error at %s", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/shipyardapp/gooptions/model"
+)
+
+// modelModulePath is the path of the module providing the model package.
+var modelModulePath = strings.TrimSuffix(reflect.TypeOf(model.Model{}).PkgPath(), "/model")
+
+// ReflectWorkspace is a temporary Go workspace the reflection programs are built
+// in, so the user's directories are left untouched. The workspace uses the
+// program's own module, the module of the target package, the modules of the
+// workspace the target package belongs to, if any, and a copy of the model
+// package when none of them provide it. Requirements and replace directives of
+// the target's module are resolved as they are when building the target.
+type ReflectWorkspace struct {
+	// Dir is the temporary directory holding the workspace.
+	Dir string
+
+	// ProgramDir is the directory of the reflection program's module.
+	ProgramDir string
+
+	// GoWork is the go.work file of the workspace.
+	GoWork string
+
+	// Build is the go command configuration.
+	Build *BuildConfig
+}
+
+// goModule is a module as printed by go list -m -json.
+type goModule struct {
+	Path      string
+	Dir       string
+	GoVersion string
+}
+
+// goWork is a go.work file as printed by go work edit -json.
+type goWork struct {
+	Go  string
+	Use []struct {
+		DiskPath string
+	}
+	Replace []struct {
+		Old, New struct {
+			Path    string
+			Version string
+		}
+	}
+}
+
+func NewReflectWorkspace(bc *BuildConfig, tp *TargetPackage) (*ReflectWorkspace, error) {
+	modules, err := listMainModules(bc, tp.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find module of %v: %v", tp.Dir, err)
+	}
+
+	userGoWork, err := goEnv(bc, tp.Dir, "GOWORK")
+	if err != nil {
+		return nil, err
+	}
+
+	tempDir, err := ioutil.TempDir("", "modelreflect")
+	if err != nil {
+		return nil, err
+	}
+	w := &ReflectWorkspace{
+		Dir:        tempDir,
+		ProgramDir: filepath.Join(tempDir, "modelreflect"),
+		GoWork:     filepath.Join(tempDir, "go.work"),
+		Build:      bc,
+	}
+	if err := w.write(modules, userGoWork); err != nil {
+		w.Remove()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *ReflectWorkspace) write(modules []*goModule, userGoWork string) error {
+	var gw *goWork
+	if userGoWork != "" && userGoWork != "off" {
+		var err error
+		if gw, err = readGoWork(w.Build, userGoWork); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(w.ProgramDir, 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(
+		filepath.Join(w.ProgramDir, "go.mod"),
+		[]byte("module gooptions.modelreflect\n\ngo 1.16\n"),
+		0666,
+	); err != nil {
+		return err
+	}
+
+	// The go version of a workspace must be at least the versions of its
+	// modules, and go.work files need go 1.18.
+	goVersion := "1.18"
+	for _, m := range modules {
+		goVersion = maxGoVersion(goVersion, m.GoVersion)
+	}
+	if gw != nil {
+		goVersion = maxGoVersion(goVersion, gw.Go)
+	}
+
+	goWorkFile := &bytes.Buffer{}
+	fmt.Fprintf(goWorkFile, "go %s\n\n", goVersion)
+	fmt.Fprintf(goWorkFile, "use %q\n", w.ProgramDir)
+
+	hasModel := false
+	for _, m := range modules {
+		fmt.Fprintf(goWorkFile, "use %q\n", m.Dir)
+		hasModel = hasModel || m.Path == modelModulePath
+	}
+
+	if !hasModel {
+		modelDir := filepath.Join(w.Dir, "gooptions")
+		if err := writeModelModule(modelDir); err != nil {
+			return err
+		}
+		fmt.Fprintf(goWorkFile, "use %q\n", modelDir)
+	}
+
+	if gw != nil {
+		writeGoWorkReplaces(goWorkFile, gw, userGoWork)
+		if err := copyFile(userGoWork+".sum", w.GoWork+".sum"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(w.GoWork, goWorkFile.Bytes(), 0666)
+}
+
+// Remove removes the workspace's directory.
+func (w *ReflectWorkspace) Remove() {
+	if err := os.RemoveAll(w.Dir); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to remove temp directory: %v\n", w.Dir)
+	}
+}
+
+// BuildCommand returns the go build command subcommand with args run in dir
+// using the workspace.
+func (w *ReflectWorkspace) BuildCommand(dir, subcommand string, args ...string) *exec.Cmd {
+	cmd := w.Build.BuildCommand(dir, subcommand, args...)
+	cmd.Env = append(cmd.Env, "GOWORK="+w.GoWork, "GOFLAGS="+workspaceGoFlags(w.Build.goFlags()))
+	return cmd
+}
+
+// workspaceGoFlags returns goflags without the -mod flag, which workspaces do
+// not accept.
+func workspaceGoFlags(goflags string) string {
+	result := []string{}
+	for _, flag := range strings.Fields(goflags) {
+		if !strings.HasPrefix(flag, "-mod=") && !strings.HasPrefix(flag, "--mod=") {
+			result = append(result, flag)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// listMainModules returns the main modules of the build in dir, which are the
+// module containing dir, or every module of its workspace.
+func listMainModules(bc *BuildConfig, dir string) ([]*goModule, error) {
+	cmd := bc.Command(dir, "list", "-m", "-json")
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*goModule{}
+	d := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var m goModule
+		if err := d.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if m.Dir == "" {
+			return nil, fmt.Errorf("module %v has no directory", m.Path)
+		}
+		result = append(result, &m)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("not in a module")
+	}
+	return result, nil
+}
+
+func goEnv(bc *BuildConfig, dir, name string) (string, error) {
+	cmd := bc.Command(dir, "env", name)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// goEnvs returns the values of the go environment variables names.
+func goEnvs(bc *BuildConfig, dir string, names ...string) ([]string, error) {
+	cmd := bc.Command(dir, append([]string{"env"}, names...)...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	values := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	if len(values) != len(names) {
+		return nil, fmt.Errorf("wrong number of go env values %v for %v", len(values), names)
+	}
+	return values, nil
+}
+
+// readGoWork reads the go.work file goWorkPath.
+func readGoWork(bc *BuildConfig, goWorkPath string) (*goWork, error) {
+	cmd := bc.Command("", "work", "edit", "-json", goWorkPath)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	gw := &goWork{}
+	if err := json.Unmarshal(output, gw); err != nil {
+		return nil, err
+	}
+	return gw, nil
+}
+
+// writeGoWorkReplaces writes the replace directives of gw, the go.work file
+// goWorkPath, to w, with relative paths made absolute.
+func writeGoWorkReplaces(w io.Writer, gw *goWork, goWorkPath string) {
+	for _, r := range gw.Replace {
+		old := r.Old.Path
+		if r.Old.Version != "" {
+			old += " " + r.Old.Version
+		}
+		new := r.New.Path
+		if r.New.Version != "" {
+			new += " " + r.New.Version
+		} else {
+			if !filepath.IsAbs(new) {
+				new = filepath.Join(filepath.Dir(goWorkPath), new)
+			}
+			new = fmt.Sprintf("%q", new)
+		}
+		fmt.Fprintf(w, "replace %s => %s\n", old, new)
+	}
+}
+
+// maxGoVersion returns the later of the go versions a and b, such as "1.21" or
+// "1.21.3". An empty version is earlier than any other.
+func maxGoVersion(a, b string) string {
+	if compareGoVersions(a, b) < 0 {
+		return b
+	}
+	return a
+}
+
+// compareGoVersions returns -1, 0 or 1 as the go version a is earlier than,
+// the same as or later than b. Prereleases, such as "1.21rc1", are compared by
+// their release only.
+func compareGoVersions(a, b string) int {
+	as, bs := goVersionNumbers(a), goVersionNumbers(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func goVersionNumbers(v string) []int {
+	result := []int{}
+	for _, part := range strings.Split(v, ".") {
+		n := 0
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				break
+			}
+			n = n*10 + int(c-'0')
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// writeModelModule writes a module providing the model package to dir.
+func writeModelModule(dir string) error {
+	modelDir := filepath.Join(dir, "model")
+	if err := os.MkdirAll(modelDir, 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(
+		filepath.Join(dir, "go.mod"),
+		[]byte(fmt.Sprintf("module %s\n\ngo 1.16\n", modelModulePath)),
+		0666,
+	); err != nil {
+		return err
+	}
+
+	return fs.WalkDir(model.Source, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		contents, err := model.Source.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(modelDir, path), contents, 0666)
+	})
+}
+
+func copyFile(src, dst string) error {
+	contents, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, contents, 0666)
+}
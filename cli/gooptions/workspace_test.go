@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestMaxGoVersion(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want string
+	}{
+		{"1.18", "1.16", "1.18"},
+		{"1.18", "1.21", "1.21"},
+		{"1.21", "1.21.3", "1.21.3"},
+		{"1.9", "1.10", "1.10"},
+		{"1.22rc1", "1.21.5", "1.22rc1"},
+		{"1.18", "", "1.18"},
+	} {
+		if got := maxGoVersion(test.a, test.b); got != test.want {
+			t.Errorf("maxGoVersion(%q, %q) = %q, want %q", test.a, test.b, got, test.want)
+		}
+	}
+}
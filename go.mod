@@ -1,5 +1,3 @@
 module github.com/shipyardapp/gooptions
 
-go 1.16
-
-require golang.org/x/tools v0.1.7 // indirect
+go 1.22
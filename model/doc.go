@@ -0,0 +1,91 @@
+// Package model describes the options gooptions generates for struct types, and
+// renders them to Go source with text/template.
+//
+// # Templates
+//
+// A file is rendered by executing GenerateTemplate with a *File. It is made of
+// blocks that user supplied templates, see Template, can replace by defining
+// a template of the same name:
+//
+//	header   the license, generated code header and //go:build line, with a *File
+//	imports  the import declaration, with a *File
+//	model    the options of a struct type, with a *Model
+//	type     the option type and its with method, with a *Model
+//	option   the option function of a field, with an *OptionData
+//
+// The default blocks remain available as "default.<name>", so a template
+// can wrap them:
+//
+//	{{ define "option" -}}
+//	{{ with .Field.Doc }}{{ comment . }}
+//	{{ end -}}
+//	{{ template "default.option" . }}
+//	{{- end }}
+//
+// A template with content outside of its definitions replaces the whole file
+// instead, and is executed with a *File.
+//
+// The data model is the exported fields and methods of File, Model, Options,
+// Field, StructField and the types implementing Type, such as .Fields,
+// .ReceiverName, .Options.OptionName, .Field.FuncName or .Field.ArgumentType.
+//
+// # Functions
+//
+// In addition to the functions predefined by text/template, templates may use
+// the functions of TemplateFuncs:
+//
+//	ArgumentName name   the parameter name for the field name
+//	ExportedName name   name spelled as an exported identifier
+//	ReceiverName name   the receiver name for the type name
+//	Title name          name with its first letter in upper case, without
+//	                    the initialisms of ExportedName
+//	join elems sep      strings.Join
+//	comment text        text as // comment lines, or "" for blank text
+//	typeString type     type as written in the generated file
+//	qualifier path      the name the package path is referred to by in the
+//	                    generated file, "" for the generated package itself
+//	kind type           "named", "predeclared", "pointer", "slice", "array",
+//	                    "map", "chan" or "func"
+//	elem type           the element type of a pointer, slice, array, map or
+//	                    chan type, or nil
+//	isNamed type        kind type is "named", and likewise isPredeclared,
+//	                    isPointer, isSlice, isArray, isMap, isChan and isFunc
+//
+// ArgumentName and ExportedName spell names with the initialisms of the options
+// of the first model of the file. The types of a combined file may have
+// initialisms of their own, which the methods of the options of each model
+// use, such as {{ $model.Options.ExportedName .Name }}.
+//
+// Templates are checked before they are executed: calls of undefined templates
+// and definitions that are never called, which are usually misspelled block
+// names, are reported with their file and line, as are syntax and execution
+// errors.
+//
+// # Plugins
+//
+// Instead of rendering the files, gooptions can hand their models to a plugin,
+// the executable gooptions-gen-<name> selected with -plugin <name>. The plugin
+// reads a PluginRequest as JSON from its standard input and writes a
+// PluginResponse as JSON to its standard output, see ReadPluginRequest and
+// WritePluginResponse. The files it returns replace the files of gooptions, and
+// should be marked as generated code, see IsGeneratedCode, so they can be
+// overwritten later.
+//
+// The documents are versioned by SchemaVersion. Models are encoded with the
+// field names of their Go types, except for the types implementing Type, which
+// are encoded as objects with a Kind, see TypeKind, and the fields the kind
+// requires:
+//
+//	{"Kind": "named", "Package": {"Path": "time", "Name": "time"}, "Name": "Duration"}
+//	{"Kind": "predeclared", "Name": "string"}
+//	{"Kind": "pointer", "Elem": {...}}
+//	{"Kind": "slice", "Elem": {...}}
+//	{"Kind": "array", "Len": 4, "Elem": {...}}
+//	{"Kind": "map", "Key": {...}, "Elem": {...}}
+//	{"Kind": "chan", "ChanDir": "<-chan", "Elem": {...}}
+//	{"Kind": "func", "In": [{"Type": {...}, "Variadic": true}], "Out": [...]}
+//
+// A Field is encoded with its StructField in a StructField member rather than
+// flattened. The reflection programs of gooptions hand their models over in
+// the same encoding, see EncodeStructTypes.
+package model
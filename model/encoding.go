@@ -0,0 +1,51 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// structTypesDocument is the JSON document of the models of struct types.
+type structTypesDocument struct {
+	Version     int
+	StructTypes []*StructType
+}
+
+// EncodeStructTypes writes the models of the struct types rts to w. It is used
+// by the reflection programs to hand the models to gooptions.
+func EncodeStructTypes(w io.Writer, rts []reflect.Type) error {
+	sts := make([]*StructType, 0, len(rts))
+	for _, rt := range rts {
+		st, err := NewStructTypeFromReflectType(rt)
+		if err != nil {
+			return err
+		}
+		sts = append(sts, st)
+	}
+	return WriteStructTypes(w, sts)
+}
+
+// WriteStructTypes writes the models sts to w as a JSON document of
+// SchemaVersion.
+func WriteStructTypes(w io.Writer, sts []*StructType) error {
+	return json.NewEncoder(w).Encode(&structTypesDocument{
+		Version:     SchemaVersion,
+		StructTypes: sts,
+	})
+}
+
+// DecodeStructTypes reads the models written by WriteStructTypes from r. The
+// reflection programs of versions of gooptions before SchemaVersion encoded
+// their models with encoding/gob, which cannot be read, so the reflection
+// programs must be built with the model package of the same version.
+func DecodeStructTypes(r io.Reader) ([]*StructType, error) {
+	var d structTypesDocument
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(d.Version); err != nil {
+		return nil, err
+	}
+	return d.StructTypes, nil
+}
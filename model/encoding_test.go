@@ -0,0 +1,71 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type testKinds struct {
+	testEmbedding
+	Pointer  *testBase
+	Slice    []string
+	Array    [0]byte
+	Map      map[string][]int
+	Chan     <-chan error
+	Func     func(string, ...interface{}) (int, error)
+	Count    atomic.Int64
+	Callback atomic.Value
+}
+
+func TestStructTypesRoundTrip(t *testing.T) {
+	st, err := NewStructTypeFromReflectType(reflect.TypeOf(testKinds{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	if err := WriteStructTypes(b, []*StructType{st}); err != nil {
+		t.Fatal(err)
+	}
+	sts, err := DecodeStructTypes(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sts) != 1 || !reflect.DeepEqual(sts[0], st) {
+		got, _ := json.Marshal(sts)
+		t.Errorf("DecodeStructTypes() = %s, want %s", got, b.Bytes())
+	}
+
+	// The fields of a model survive the round trip too, with the allocations
+	// of the promoted fields.
+	options := NewOptions()
+	options.Embedded = EmbeddedPromote
+	m := NewModel(options, &Package{Path: "example.com/app", Name: "app"}, st)
+	mb, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Model
+	if err := json.Unmarshal(mb, &got); err != nil {
+		t.Fatal(err)
+	}
+	allocations := 0
+	for _, field := range m.Fields {
+		allocations += len(field.Allocations)
+	}
+	if allocations == 0 {
+		t.Error("no fields with allocations")
+	}
+	if !reflect.DeepEqual(got.Fields, m.Fields) {
+		t.Errorf("fields after round trip = %+v, want %+v", got.Fields, m.Fields)
+	}
+
+	_, err = DecodeStructTypes(strings.NewReader(`{"Version": 0}`))
+	if err == nil {
+		t.Error("DecodeStructTypes() of version 0 succeeded")
+	}
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"fmt"
+)
+
+// EmbeddedMode is how options are generated for embedded fields.
+type EmbeddedMode string
+
+const (
+	// EmbeddedValue generates an option setting the whole embedded value.
+	EmbeddedValue EmbeddedMode = "value"
+
+	// EmbeddedPromote generates an option for each promoted field of embedded
+	// struct types, allocating nil embedded pointers on the way. Embedded types
+	// that are not structs are handled like EmbeddedValue.
+	EmbeddedPromote EmbeddedMode = "promote"
+
+	// EmbeddedSkip generates no options for embedded fields.
+	EmbeddedSkip EmbeddedMode = "skip"
+)
+
+// ParseEmbeddedMode returns the EmbeddedMode named s.
+func ParseEmbeddedMode(s string) (EmbeddedMode, error) {
+	switch m := EmbeddedMode(s); m {
+	case EmbeddedValue, EmbeddedPromote, EmbeddedSkip:
+		return m, nil
+	}
+	return "", fmt.Errorf("model: unknown embedded mode %q", s)
+}
+
+// Field is a field of the struct type, or promoted to it, to generate an
+// option for.
+type Field struct {
+	*StructField
+
+	// Selector of the field from the struct, such as "Name", or "Base.Name"
+	// for a field promoted from the embedded field Base.
+	Selector string
+
+	// Allocations of the nil embedded pointers on the way to a promoted field,
+	// outermost first.
+	Allocations []*Allocation
+
+	// FuncName is the name of the option function.
+	FuncName string
+
+	// ArgumentName is the name of the option function's parameter.
+	ArgumentName string
+}
+
+// Allocation is an embedded pointer field that is allocated if nil before a
+// field promoted through it is set.
+type Allocation struct {
+	// Selector of the embedded pointer field from the struct.
+	Selector string
+
+	// ElementType is the type the pointer points to.
+	ElementType Type
+}
+
+// ArgumentType is the type of the option function's parameter.
+func (f *Field) ArgumentType() Type {
+	if f.StoreType != nil {
+		return f.StoreType
+	}
+	return f.Type
+}
+
+func (f *Field) getImports() []*Package {
+	result := f.ArgumentType().getImports()
+	for _, a := range f.Allocations {
+		result = append(result, a.ElementType.getImports()...)
+	}
+	return result
+}
+
+func getFieldsImports(fields []*Field) []*Package {
+	result := []*Package{}
+	for _, field := range fields {
+		result = append(result, field.getImports()...)
+	}
+	return result
+}
+
+// selectFields returns the fields of st to generate options for in declaration
+// order, and diagnostics for the fields that are skipped. Promoted fields that
+// are shadowed by a shallower field or that are ambiguous are left out, as they
+// are in Go.
+func selectFields(options *Options, st *StructType) ([]*Field, []string) {
+	type candidate struct {
+		field *Field
+		depth int
+	}
+
+	candidates := []*candidate{}
+	depths := map[string][]int{}
+	diagnostics := []string{}
+
+	var collect func(sfs []*StructField, prefix string, allocations []*Allocation, depth int)
+	collect = func(sfs []*StructField, prefix string, allocations []*Allocation, depth int) {
+		for _, sf := range sfs {
+			if sf.Name == "_" {
+				continue
+			}
+			depths[sf.Name] = append(depths[sf.Name], depth)
+
+			selector := prefix + sf.Name
+			if sf.Embedded {
+				if options.Embedded == EmbeddedSkip {
+					continue
+				}
+				if options.Embedded == EmbeddedPromote && sf.PromotesFields {
+					embeddedAllocations := allocations
+					if pt, ok := sf.Type.(*PointerType); ok {
+						embeddedAllocations = append(append([]*Allocation{}, allocations...), &Allocation{
+							Selector:    selector,
+							ElementType: pt.ElementType,
+						})
+					}
+					collect(sf.Fields, selector+".", embeddedAllocations, depth+1)
+					continue
+				}
+			}
+
+			if sf.CopyLock != "" && sf.StoreType == nil && !options.CopyLocks {
+				diagnostics = append(diagnostics, fmt.Sprintf("skipping field %s%s: %s must not be copied", prefix, sf.Name, sf.CopyLock))
+				continue
+			}
+
+			candidates = append(candidates, &candidate{
+				field: &Field{
+					StructField: sf,
+					Selector:    selector,
+					Allocations: allocations,
+					FuncName:    options.FuncName(sf.Name),
+				},
+				depth: depth,
+			})
+		}
+	}
+	collect(st.Fields, "", nil, 0)
+
+	result := []*Field{}
+	for _, c := range candidates {
+		if isVisibleAt(depths[c.field.Name], c.depth) {
+			result = append(result, c.field)
+		}
+	}
+	return result, diagnostics
+}
+
+// isVisibleAt reports whether a field at depth is the only shallowest one of
+// the fields of the same name at depths.
+func isVisibleAt(depths []int, depth int) bool {
+	n := 0
+	for _, d := range depths {
+		if d < depth {
+			return false
+		}
+		if d == depth {
+			n++
+		}
+	}
+	return n == 1
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testBase struct {
+	ID   string
+	Name string
+}
+
+type testAudit struct {
+	*testBase
+	CreatedAt int
+}
+
+type testEmbedding struct {
+	testBase
+	*testAudit
+	Name string
+	_    int
+}
+
+func TestSelectFields(t *testing.T) {
+	st, err := NewStructTypeFromReflectType(reflect.TypeOf(testEmbedding{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mode      EmbeddedMode
+		selectors []string
+	}{
+		{EmbeddedValue, []string{"testBase", "testAudit", "Name"}},
+		{EmbeddedPromote, []string{"testBase.ID", "testAudit.CreatedAt", "Name"}},
+		{EmbeddedSkip, []string{"Name"}},
+	}
+	for _, test := range tests {
+		options := NewOptions()
+		options.Embedded = test.mode
+
+		selectors := []string{}
+		fields, _ := selectFields(options, st)
+		for _, field := range fields {
+			selectors = append(selectors, field.Selector)
+		}
+		if !reflect.DeepEqual(selectors, test.selectors) {
+			t.Errorf("selectFields(%v) = %v, want %v", test.mode, selectors, test.selectors)
+		}
+	}
+}
+
+func TestSelectFieldsCopyLocks(t *testing.T) {
+	st, err := NewStructTypeFromReflectType(reflect.TypeOf(testCopyLocks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selectors := func(fields []*Field) []string {
+		result := []string{}
+		for _, field := range fields {
+			result = append(result, field.Selector)
+		}
+		return result
+	}
+
+	fields, diagnostics := selectFields(NewOptions(), st)
+	if want := []string{"LockPtr", "Retry", "Int", "Builder", "Time", "Locker", "Count", "Value", "Name"}; !reflect.DeepEqual(selectors(fields), want) {
+		t.Errorf("selectFields() = %v, want %v", selectors(fields), want)
+	}
+	wantDiagnostics := []string{
+		"skipping field Mutex: sync.Mutex must not be copied",
+		"skipping field WaitGroup: sync.noCopy in sync.WaitGroup must not be copied",
+		"skipping field Locked: sync.Mutex in model.testLocked must not be copied",
+		"skipping field Locks: sync.Mutex in [2]sync.Mutex must not be copied",
+		"skipping field Buffer: bytes.Buffer must not be copied",
+		"skipping field Encoder: json.Encoder must not be copied",
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Errorf("selectFields() diagnostics = %q, want %q", diagnostics, wantDiagnostics)
+	}
+
+	options := NewOptions()
+	options.CopyLocks = true
+	fields, diagnostics = selectFields(options, st)
+	if len(fields) != len(st.Fields) || len(diagnostics) != 0 {
+		t.Errorf("selectFields() with CopyLocks = %v, %q, want all fields", selectors(fields), diagnostics)
+	}
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+// fingerprintPrefix starts the header line recording the fingerprint of a
+// generated file.
+const fingerprintPrefix = "// gooptions fingerprint: "
+
+// GeneratorVersion is recorded in the fingerprints of generated files. It
+// must be incremented whenever the files generated from the same models with
+// the same options change, so files generated before are found out of date.
+const GeneratorVersion = 2
+
+// Fingerprint returns a hash of everything the contents of f are generated
+// from: the struct models, the options they are generated with and
+// GeneratorVersion. It depends on neither the location of the package nor the
+// machine, so the files of a package are found up to date wherever they are
+// checked.
+func (f *File) Fingerprint() string {
+	type fingerprintModel struct {
+		Options    *Options
+		StructType *StructType
+	}
+	data := struct {
+		Version int
+		Package *Package
+		Models  []fingerprintModel
+	}{
+		Version: GeneratorVersion,
+		Package: f.Package,
+	}
+	for _, m := range f.Models {
+		// The names of templates are only used in error messages, and are
+		// usually the paths of their files.
+		o := *m.Options
+		o.Templates = nil
+		for _, t := range m.Options.Templates {
+			o.Templates = append(o.Templates, &Template{Text: t.Text})
+		}
+		data.Models = append(data.Models, fingerprintModel{&o, m.StructType})
+	}
+	return hashJSON(data)
+}
+
+func hashJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		// The models are plain data, so this is a bug.
+		panic(err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadFingerprint returns the fingerprint recorded in the header of the
+// generated file src, or "" if it has none.
+func ReadFingerprint(src []byte) string {
+	if !IsGeneratedFile(src) {
+		return ""
+	}
+	fingerprint, _, _ := findFingerprintLine(src)
+	return fingerprint
+}
+
+// FingerprintUpToDate reports whether the file at path records fingerprint and
+// is unchanged since it was generated, according to the checksum recorded
+// next to it. Files edited after they were generated are out of date.
+func FingerprintUpToDate(path, fingerprint string) bool {
+	src, err := ioutil.ReadFile(path)
+	if err != nil || ReadFingerprint(src) != fingerprint {
+		return false
+	}
+	_, checksum, _ := findFingerprintLine(src)
+	return checksum != "" && checksum == sourceChecksum(src)
+}
+
+// findFingerprintLine returns the fingerprint and checksum recorded in the
+// header of src, and the offsets of the line recording them, or -1.
+func findFingerprintLine(src []byte) (fingerprint, checksum string, offsets [2]int) {
+	offset := 0
+	for _, line := range strings.SplitAfter(string(src), "\n") {
+		start := offset
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !strings.HasPrefix(line, fingerprintPrefix) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, fingerprintPrefix))
+		if len(fields) > 0 {
+			fingerprint = fields[0]
+		}
+		if len(fields) > 1 {
+			checksum = fields[1]
+		}
+		return fingerprint, checksum, [2]int{start, start + len(line)}
+	}
+	return "", "", [2]int{-1, -1}
+}
+
+// sourceChecksum returns the checksum of the generated file src, which is a
+// hash of src with the fingerprint line recording the fingerprint alone.
+func sourceChecksum(src []byte) string {
+	fingerprint, _, offsets := findFingerprintLine(src)
+	if offsets[0] < 0 {
+		return ""
+	}
+	h := sha256.New()
+	h.Write(src[:offsets[0]])
+	h.Write([]byte(fingerprintPrefix + fingerprint))
+	h.Write(src[offsets[1]:])
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// sealFingerprint returns src with its checksum recorded next to its
+// fingerprint, so edits of the generated file are detected.
+func sealFingerprint(src []byte) []byte {
+	fingerprint, _, offsets := findFingerprintLine(src)
+	if offsets[0] < 0 || fingerprint == "" {
+		return src
+	}
+	result := append([]byte{}, src[:offsets[0]]...)
+	result = append(result, fingerprintPrefix+fingerprint+" "+sourceChecksum(src)...)
+	return append(result, src[offsets[1]:]...)
+}
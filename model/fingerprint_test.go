@@ -0,0 +1,79 @@
+package model
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	p := &Package{Name: "example", Path: "example.com/example"}
+	st := &StructType{
+		Name: "User",
+		Fields: []*StructField{
+			{Name: "Name", Type: PredeclaredType("string")},
+		},
+	}
+
+	f := NewModel(NewOptions(), p, st).File()
+	fingerprint := f.Fingerprint()
+	if again := NewModel(NewOptions(), p, st).File().Fingerprint(); again != fingerprint {
+		t.Errorf("Fingerprint() = %q, then %q for the same model", fingerprint, again)
+	}
+
+	options := NewOptions()
+	options.OptionPrefix = "Set"
+	if other := NewModel(options, p, st).File().Fingerprint(); other == fingerprint {
+		t.Errorf("Fingerprint() = %q for different options", other)
+	}
+
+	// Templates are told apart by their text, not by the paths they are read
+	// from.
+	options = NewOptions()
+	options.Templates = []*Template{{Name: "/home/a/option.tmpl", Text: "{{define \"option\"}}{{end}}"}}
+	withTemplate := NewModel(options, p, st).File().Fingerprint()
+	options.Templates = []*Template{{Name: "option.tmpl", Text: "{{define \"option\"}}{{end}}"}}
+	if other := NewModel(options, p, st).File().Fingerprint(); other != withTemplate {
+		t.Errorf("Fingerprint() = %q, then %q for a template read from another path", withTemplate, other)
+	}
+
+	src := []byte(GeneratedHeader + "\n" + fingerprintPrefix + fingerprint + "\n\npackage example\n")
+	if got := ReadFingerprint(src); got != fingerprint {
+		t.Errorf("ReadFingerprint() = %q, want %q", got, fingerprint)
+	}
+	if got := ReadFingerprint([]byte("package example\n\n" + fingerprintPrefix + fingerprint + "\n")); got != "" {
+		t.Errorf("ReadFingerprint() = %q for a file not generated by gooptions", got)
+	}
+}
+
+func TestFingerprintUpToDate(t *testing.T) {
+	st := &StructType{Name: "User"}
+	fingerprint := NewModel(NewOptions(), &Package{Name: "example", Path: "example.com/example"}, st).File().Fingerprint()
+	src := sealFingerprint([]byte(GeneratedHeader + "\n" + fingerprintPrefix + fingerprint + "\n\npackage example\n\nconst A = 1\n"))
+	if got := ReadFingerprint(src); got != fingerprint {
+		t.Errorf("ReadFingerprint() = %q of a sealed file, want %q", got, fingerprint)
+	}
+
+	for _, test := range []struct {
+		name        string
+		src         []byte
+		fingerprint string
+		want        bool
+	}{
+		{"sealed", src, fingerprint, true},
+		{"other fingerprint", src, "other", false},
+		{"edited", bytes.Replace(src, []byte("A = 1"), []byte("A = 2"), 1), fingerprint, false},
+		{"unsealed", []byte(GeneratedHeader + "\n" + fingerprintPrefix + fingerprint + "\n\npackage example\n"), fingerprint, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "example_options.go")
+			if err := ioutil.WriteFile(path, test.src, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := FingerprintUpToDate(path, test.fingerprint); got != test.want {
+				t.Errorf("FingerprintUpToDate() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormatSource formats the Go source src like goimports: imports not used by
+// src are removed, the standard library imports are grouped before the others,
+// and the result is gofmt'ed. Package names are guessed from import paths, see
+// GuessPackageName, and imports whose name cannot be guessed are kept. If src
+// does not parse, the error is a *SyntaxError.
+func FormatSource(src []byte) ([]byte, error) {
+	return formatSource(src, nil)
+}
+
+// formatSource is FormatSource with the names of imported packages by path,
+// which are used rather than guesses.
+func formatSource(src []byte, names map[string]string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, newSyntaxError(src, err)
+	}
+
+	src = rewriteImports(fset, file, src, names)
+
+	result, err := format.Source(src)
+	if err != nil {
+		return nil, newSyntaxError(src, err)
+	}
+	return result, nil
+}
+
+// rewriteImports returns src with its import declarations replaced by a single
+// one of the used imports, grouped and sorted by path, or removed if none are
+// used.
+func rewriteImports(fset *token.FileSet, file *ast.File, src []byte, names map[string]string) []byte {
+	packageName := func(importPath string) string {
+		if known, ok := names[importPath]; ok {
+			return known
+		}
+		return GuessPackageName(importPath)
+	}
+	used := usedImports(fset, file, packageName)
+
+	std, other := []string{}, []string{}
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		name := packageName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." && token.IsIdentifier(name) && !used[name] {
+			continue
+		}
+
+		line := strconv.Quote(importPath)
+		if spec.Name != nil {
+			line = spec.Name.Name + " " + line
+		}
+		if isStandardImportPath(importPath) {
+			std = append(std, line)
+		} else {
+			other = append(other, line)
+		}
+	}
+	byPath := func(lines []string) {
+		sort.Slice(lines, func(i, j int) bool {
+			return importLinePath(lines[i]) < importLinePath(lines[j])
+		})
+	}
+	byPath(std)
+	byPath(other)
+
+	b := &bytes.Buffer{}
+	switch {
+	case len(std)+len(other) == 0:
+	case len(std)+len(other) == 1:
+		fmt.Fprintf(b, "import %s\n", strings.Join(append(std, other...), ""))
+	default:
+		b.WriteString("import (\n")
+		for _, line := range std {
+			fmt.Fprintf(b, "\t%s\n", line)
+		}
+		if len(std) > 0 && len(other) > 0 {
+			b.WriteString("\n")
+		}
+		for _, line := range other {
+			fmt.Fprintf(b, "\t%s\n", line)
+		}
+		b.WriteString(")\n")
+	}
+
+	// The import declarations are the first declarations of the file.
+	var start, end token.Pos
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			break
+		}
+		if start == token.NoPos {
+			start = gd.Pos()
+		}
+		end = gd.End()
+	}
+	if start == token.NoPos {
+		return src
+	}
+	startOffset := fset.Position(start).Offset
+	endOffset := fset.Position(end).Offset
+
+	result := append([]byte{}, src[:startOffset]...)
+	result = append(result, b.Bytes()...)
+	return append(result, src[endOffset:]...)
+}
+
+// importLinePath returns the path of the import spec line.
+func importLinePath(line string) string {
+	return line[strings.Index(line, `"`):]
+}
+
+// usedImports returns the names of the imports of file that are referred to,
+// resolved by type-checking file with empty packages named by packageName in
+// place of the imported ones, so identifiers declared in scope are told apart
+// from package qualifiers. The declarations of the other files of the package
+// are unknown, so type errors are expected and ignored.
+func usedImports(fset *token.FileSet, file *ast.File, packageName func(importPath string) string) map[string]bool {
+	info := &types.Info{Uses: map[*ast.Ident]types.Object{}}
+	config := &types.Config{
+		Importer: importerFunc(func(importPath string) (*types.Package, error) {
+			pkg := types.NewPackage(importPath, packageName(importPath))
+			pkg.MarkComplete()
+			return pkg, nil
+		}),
+		Error: func(error) {},
+	}
+	config.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	result := map[string]bool{}
+	for _, obj := range info.Uses {
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			result[pkgName.Name()] = true
+		}
+	}
+	return result
+}
+
+type importerFunc func(importPath string) (*types.Package, error)
+
+func (f importerFunc) Import(importPath string) (*types.Package, error) {
+	return f(importPath)
+}
+
+// isStandardImportPath reports whether importPath is of the standard library,
+// whose first element has no dot.
+func isStandardImportPath(importPath string) bool {
+	first := importPath
+	if i := strings.Index(importPath, "/"); i >= 0 {
+		first = importPath[:i]
+	}
+	return !strings.Contains(first, ".")
+}
+
+// syntaxErrorContext is the number of lines shown around a syntax error.
+const syntaxErrorContext = 2
+
+// SyntaxError is a syntax error in generated source, which is most likely caused
+// by a template.
+type SyntaxError struct {
+	// Errors are the errors of the parser.
+	Errors scanner.ErrorList
+
+	// Source is the source that does not parse.
+	Source []byte
+}
+
+func newSyntaxError(src []byte, err error) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		return err
+	}
+	return &SyntaxError{Errors: list, Source: src}
+}
+
+// Error returns the errors, each followed by the lines around it with the
+// offending column marked.
+func (e *SyntaxError) Error() string {
+	lines := strings.Split(string(e.Source), "\n")
+
+	b := &strings.Builder{}
+	b.WriteString("generated source does not parse:")
+	for i, err := range e.Errors {
+		if i == 10 {
+			fmt.Fprintf(b, "\n(and %d more errors)", len(e.Errors)-i)
+			break
+		}
+		fmt.Fprintf(b, "\n%d:%d: %s", err.Pos.Line, err.Pos.Column, err.Msg)
+
+		first := err.Pos.Line - syntaxErrorContext
+		if first < 1 {
+			first = 1
+		}
+		last := err.Pos.Line + syntaxErrorContext
+		if last > len(lines) {
+			last = len(lines)
+		}
+		for n := first; n <= last; n++ {
+			marker := " "
+			if n == err.Pos.Line {
+				marker = ">"
+			}
+			fmt.Fprintf(b, "\n%s %4d | %s", marker, n, lines[n-1])
+			if n == err.Pos.Line && err.Pos.Column > 0 {
+				// Tabs are kept so the caret lines up with the column.
+				prefix := lines[n-1]
+				if err.Pos.Column-1 < len(prefix) {
+					prefix = prefix[:err.Pos.Column-1]
+				}
+				indent := strings.Map(func(r rune) rune {
+					if r == '\t' {
+						return r
+					}
+					return ' '
+				}, prefix)
+				fmt.Fprintf(b, "\n       | %s^", indent)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatSource(t *testing.T) {
+	src := `package example
+
+import (
+	"example.com/lib/v2"
+	"time"
+	yaml "gopkg.in/yaml.v3"
+	"fmt"
+	_ "embed"
+)
+
+type Option func(*Config)
+
+func WithTimeout(timeout time.Duration, client *lib.Client) Option {
+	return nil
+}
+`
+	want := `package example
+
+import (
+	_ "embed"
+	"time"
+
+	"example.com/lib/v2"
+)
+
+type Option func(*Config)
+
+func WithTimeout(timeout time.Duration, client *lib.Client) Option {
+	return nil
+}
+`
+	got, err := FormatSource([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("FormatSource() = %s, want %s", got, want)
+	}
+}
+
+func TestFormatSourceSyntaxError(t *testing.T) {
+	src := "package example\n\nfunc WithName(name string) Option {\n\treturn func(c *Config) {\n\t\tc.Name = = name\n\t}\n}\n"
+
+	_, err := FormatSource([]byte(src))
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Fatalf("FormatSource() error = %v, want a *SyntaxError", err)
+	}
+	for _, want := range []string{"5:12: ", ">    5 | \t\tc.Name = = name", "\t\t         ^"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("FormatSource() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestFormatSourceShadowedImports(t *testing.T) {
+	src := `package example
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+func WithDeadline(time time.Time) Option {
+	return func(c *Config) {
+		c.Deadline = time
+	}
+}
+
+func WithTimeout(timeout int) Option {
+	return func(c *Config) {
+		time := struct{ Duration int }{timeout}
+		c.Timeout = time.Duration
+	}
+}
+
+func WithURL(url string) Option {
+	return func(c *Config) {
+		c.URL = url
+	}
+}
+
+func WithName(strings []string) Option {
+	return func(c *Config) {
+		c.Names = strings
+	}
+}
+
+func WithPrefix(prefix string) Option {
+	return func(c *Config) {
+		c.Prefix = strings.TrimSpace(prefix)
+	}
+}
+`
+	got, err := FormatSource([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "import (\n\t\"strings\"\n\t\"time\"\n)\n") {
+		t.Errorf("FormatSource() = %s, want only the strings and time imports kept", got)
+	}
+}
@@ -6,73 +6,248 @@ import (
 	"fmt"
 	"go/token"
 	"io"
+	"io/ioutil"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
-	"text/template"
 	"unicode"
 	"unicode/utf8"
 )
 
-var variableNameCounter = 0
-
 //go:embed generate.gotemplate
 var GenerateTemplate string
 
+// GeneratedHeader marks the files generated by gooptions. It follows the
+// convention of https://golang.org/s/generatedcode, so tools recognise the
+// files as generated.
+const GeneratedHeader = "// Code generated by gooptions. DO NOT EDIT."
+
+// legacyGeneratedHeader is the first line of the files generated by earlier
+// versions of gooptions.
+const legacyGeneratedHeader = "// DO NOT EDIT. This file was generated by gooptions."
+
+// IsGeneratedFile reports whether the Go source src was generated by gooptions,
+// which is when GeneratedHeader is a line before the package clause, or src
+// starts with the header of earlier versions.
+func IsGeneratedFile(src []byte) bool {
+	if bytes.HasPrefix(src, []byte(legacyGeneratedHeader+"\n")) {
+		return true
+	}
+	for _, line := range headerLines(src) {
+		if line == GeneratedHeader {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedCodeComment matches the comment marking generated Go source by the
+// convention of https://golang.org/s/generatedcode.
+var generatedCodeComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// IsGeneratedCode reports whether src was generated by any generator, which is
+// when a line before the package clause marks it as generated code. Files
+// written by plugins are expected to be marked.
+func IsGeneratedCode(src []byte) bool {
+	for _, line := range headerLines(src) {
+		if generatedCodeComment.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerLines returns the lines of src before the package clause.
+func headerLines(src []byte) []string {
+	result := []string{}
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
 func Generate(m *Model, typeName string, cwd, destinationPath string) error {
-	t := template.New("generator")
-	t = t.Funcs(
-		map[string]interface{}{
-			"ArgumentName": ArgumentName,
-			"ReceiverName": ReceiverName,
-			"Title":        strings.Title,
-		},
-	)
-
-	var err error
-	t, err = t.Parse(GenerateTemplate)
+	return GenerateFile(m.File(), typeName, cwd, destinationPath)
+}
+
+// GenerateFile generates the options of the models of f into one file. name is
+// used for the default destination path in place of a type name.
+func GenerateFile(f *File, name string, cwd, destinationPath string) error {
+	options := f.Models[0].Options
+
+	destinationPath, err := options.OutputFile(name, cwd, destinationPath)
 	if err != nil {
 		return err
 	}
 
-	b := &bytes.Buffer{}
-	templateData := m
-	if err := t.Execute(b, templateData); err != nil {
+	// A file generated from the same models and options is left untouched,
+	// so its modification time does not change.
+	if UpToDate(f, destinationPath) {
+		return nil
+	}
+
+	if err := CheckOverwrite(destinationPath, options.Force); err != nil {
 		return err
 	}
 
-	destinationPath, err = m.Options.OutputFile(typeName, cwd, destinationPath)
+	src, err := RenderFile(f)
 	if err != nil {
 		return err
 	}
-	outputFile, err := os.Create(destinationPath)
+
+	return WriteFileAtomic(destinationPath, src)
+}
+
+// NotGeneratedError is returned when generating a file would overwrite a file
+// that was not generated by gooptions, or by any generator for the files of
+// plugins.
+type NotGeneratedError struct {
+	Path string
+
+	// Code reports that the file is not marked as generated code by any
+	// generator, see IsGeneratedCode.
+	Code bool
+}
+
+func (e *NotGeneratedError) Error() string {
+	if e.Code {
+		return fmt.Sprintf("refusing to overwrite %s, which is not generated code", e.Path)
+	}
+	return fmt.Sprintf("refusing to overwrite %s, which was not generated by gooptions", e.Path)
+}
+
+// CheckOverwrite returns a *NotGeneratedError if path is a file that was not
+// generated by gooptions, unless force is set.
+func CheckOverwrite(path string, force bool) error {
+	return checkOverwrite(path, force, false)
+}
+
+// CheckOverwriteCode is CheckOverwrite for the files of plugins, which may
+// overwrite the files marked as generated code by any generator, see
+// IsGeneratedCode.
+func CheckOverwriteCode(path string, force bool) error {
+	return checkOverwrite(path, force, true)
+}
+
+func checkOverwrite(path string, force, code bool) error {
+	if force {
+		return nil
+	}
+	src, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if code && !IsGeneratedCode(src) || !code && !IsGeneratedFile(src) {
+		return &NotGeneratedError{Path: path, Code: code}
+	}
+	return nil
+}
+
+// WriteFileAtomic writes src to a temporary file next to path and renames it to
+// path, so path is never left partially written. The mode of an existing file
+// is kept.
+func WriteFileAtomic(path string, src []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(f.Name())
 
-	if err := WriteAndFormatOutputFile(b.Bytes(), outputFile); err != nil {
+	if _, err := f.Write(src); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
 		return err
 	}
+	return os.Rename(f.Name(), path)
+}
 
-	return nil
+// UpToDate reports whether the file at path was generated from the same models
+// and options as f, according to its fingerprint, and was not edited since.
+func UpToDate(f *File, path string) bool {
+	return FingerprintUpToDate(path, f.Fingerprint())
 }
 
-func WriteAndFormatOutputFile(contents []byte, f *os.File) error {
-	if _, err := io.Copy(f, bytes.NewReader(contents)); err != nil {
+// GenerateTo writes the file generating the options of m to w.
+func GenerateTo(w io.Writer, m *Model) error {
+	return GenerateFileTo(w, m.File())
+}
+
+// GenerateFileTo writes the file generating the options of the models of f to
+// w.
+func GenerateFileTo(w io.Writer, f *File) error {
+	src, err := RenderFile(f)
+	if err != nil {
 		return err
 	}
+	_, err = w.Write(src)
+	return err
+}
+
+// RenderFile returns the formatted source of the file generating the options of
+// the models of f.
+func RenderFile(f *File) ([]byte, error) {
+	t, root, err := ParseTemplates(f, f.Models[0].Options.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bytes.Buffer{}
+	templateData := f
+	if err := t.ExecuteTemplate(b, root, templateData); err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for _, imp := range f.Imports {
+		names[imp.Path] = imp.Name
+	}
+	src, err := formatSource(b.Bytes(), names)
+	if err != nil {
+		return nil, err
+	}
+	return sealFingerprint(src), nil
+}
+
+// WriteAndFormatOutputFile formats contents like FormatSource and writes them to
+// the file f, which is closed first.
+//
+// Deprecated: Use FormatSource and WriteFileAtomic, or GenerateFile.
+func WriteAndFormatOutputFile(contents []byte, f *os.File) error {
 	if err := f.Close(); err != nil {
 		return err
 	}
-
-	cmd := exec.Command("gofmt", "-w", f.Name())
-	return cmd.Run()
+	src, err := formatSource(contents, nil)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(f.Name(), src)
 }
 
+// ArgumentName returns name spelled as an unexported identifier with the
+// default initialisms, with an underscore appended to keywords.
+//
+// Deprecated: Use Options.ArgumentName, which applies the initialisms of the
+// options.
 func ArgumentName(name string) string {
-	r, size := utf8.DecodeRuneInString(name)
-	result := string(unicode.ToLower(r)) + string(name[size:])
-	return SanitizeName(result)
+	return SanitizeName(NewOptions().ArgumentName(name))
 }
 
 func ReceiverName(name string) string {
@@ -80,11 +255,12 @@ func ReceiverName(name string) string {
 	return string(unicode.ToLower(r))
 }
 
+// SanitizeName appends an underscore to keywords, so "for" becomes "for_". The
+// result only depends on name. Collisions with predeclared and other
+// identifiers are resolved by Scope.Declare.
 func SanitizeName(name string) string {
-	result := name
-	if token.IsKeyword(name) || name == "byte" || name == "rune" {
-		result = fmt.Sprintf("%s%d", name, variableNameCounter)
-		variableNameCounter++
+	if token.IsKeyword(name) {
+		return name + "_"
 	}
-	return result
+	return name
 }
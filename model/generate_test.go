@@ -0,0 +1,116 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckOverwrite(t *testing.T) {
+	dir := t.TempDir()
+
+	generated := filepath.Join(dir, "user_options.go")
+	if err := WriteFileAtomic(generated, []byte(GeneratedHeader+"\n\npackage example\n")); err != nil {
+		t.Fatal(err)
+	}
+	handWritten := filepath.Join(dir, "user.go")
+	if err := ioutil.WriteFile(handWritten, []byte("package example\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		force bool
+		ok    bool
+	}{
+		{generated, false, true},
+		{filepath.Join(dir, "missing.go"), false, true},
+		{handWritten, false, false},
+		{handWritten, true, true},
+	}
+	for _, test := range tests {
+		err := CheckOverwrite(test.path, test.force)
+		if _, notGenerated := err.(*NotGeneratedError); notGenerated == test.ok {
+			t.Errorf("CheckOverwrite(%q, %v) = %v", filepath.Base(test.path), test.force, err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("files %v left in the directory, want the 2 written", files)
+	}
+
+	otherGenerated := filepath.Join(dir, "user_docs.go")
+	if err := ioutil.WriteFile(otherGenerated, []byte("// Code generated by gooptions-gen-docs. DO NOT EDIT.\n\npackage example\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckOverwrite(otherGenerated, false); err == nil {
+		t.Errorf("CheckOverwrite(%q, false) = nil for a file of another generator", filepath.Base(otherGenerated))
+	}
+	for _, test := range []struct {
+		path string
+		ok   bool
+	}{
+		{generated, true},
+		{otherGenerated, true},
+		{handWritten, false},
+	} {
+		err := CheckOverwriteCode(test.path, false)
+		if _, notGenerated := err.(*NotGeneratedError); notGenerated == test.ok {
+			t.Errorf("CheckOverwriteCode(%q, false) = %v", filepath.Base(test.path), err)
+		}
+	}
+}
+
+func TestIsGeneratedFile(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{GeneratedHeader + "\n\npackage example\n", true},
+		{"// Copyright 2021 Example\n\n" + GeneratedHeader + "\n\npackage example\n", true},
+		{legacyGeneratedHeader + "\n\npackage example\n", true},
+		{"package example\n\n" + GeneratedHeader + "\n", false},
+		{"// Code generated by stringer. DO NOT EDIT.\n\npackage example\n", false},
+		{"package example\n", false},
+	}
+	for _, test := range tests {
+		if got := IsGeneratedFile([]byte(test.src)); got != test.want {
+			t.Errorf("IsGeneratedFile(%q) = %v, want %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestWriteAndFormatOutputFile(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "user_options.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAndFormatOutputFile([]byte("package example\n\nimport \"fmt\"\n\nconst  A  =  1\n"), f); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package example\n\nconst A = 1\n"; string(got) != want {
+		t.Errorf("WriteAndFormatOutputFile() wrote %q, want %q", got, want)
+	}
+}
+
+func TestArgumentName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Name":   "name",
+		"UserID": "userID",
+		"URL":    "url",
+		"Type":   "type_",
+	} {
+		if got := ArgumentName(name); got != want {
+			t.Errorf("ArgumentName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
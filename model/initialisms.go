@@ -0,0 +1,144 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CommonInitialisms are the initialisms Go identifiers spell in a single case,
+// as listed by golint.
+var CommonInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP",
+	"HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA",
+	"SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "UUID",
+	"URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// Namer spells identifiers made of the words of a field name, writing
+// initialisms in a single case.
+type Namer struct {
+	// Spelling of the initialisms by their upper case form.
+	initialisms map[string]string
+}
+
+// NewNamer returns a Namer for the initialisms, which are matched regardless of
+// case and written as given, such as "ID" or "OAuth".
+func NewNamer(initialisms []string) *Namer {
+	n := &Namer{
+		initialisms: map[string]string{},
+	}
+	for _, initialism := range initialisms {
+		n.initialisms[strings.ToUpper(initialism)] = initialism
+	}
+	return n
+}
+
+// Exported returns name spelled as an exported identifier, so "userId" becomes
+// "UserID" and "httpURL" becomes "HTTPURL".
+func (n *Namer) Exported(name string) string {
+	b := &strings.Builder{}
+	for _, word := range SplitWords(name) {
+		if initialism, ok := n.initialism(word); ok {
+			b.WriteString(initialism)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(word)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(word[size:])
+	}
+	return b.String()
+}
+
+// Unexported returns name spelled as an unexported identifier, so "UUID"
+// becomes "uuid" and "UserID" becomes "userID".
+func (n *Namer) Unexported(name string) string {
+	words := SplitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+
+	b := &strings.Builder{}
+	first := words[0]
+	if _, ok := n.initialism(first); ok || strings.ToUpper(first) == first {
+		b.WriteString(strings.ToLower(first))
+	} else {
+		r, size := utf8.DecodeRuneInString(first)
+		b.WriteRune(unicode.ToLower(r))
+		b.WriteString(first[size:])
+	}
+
+	for _, word := range words[1:] {
+		if initialism, ok := n.initialism(word); ok {
+			b.WriteString(initialism)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(word)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(word[size:])
+	}
+	return b.String()
+}
+
+// initialism returns word spelled as an initialism, including plurals such as
+// "IDs", and whether it is one.
+func (n *Namer) initialism(word string) (string, bool) {
+	upper := strings.ToUpper(word)
+	if initialism, ok := n.initialisms[upper]; ok {
+		return initialism, true
+	}
+	if strings.HasSuffix(word, "s") {
+		if initialism, ok := n.initialisms[upper[:len(upper)-1]]; ok {
+			return initialism + "s", true
+		}
+	}
+	return "", false
+}
+
+// SplitWords splits an identifier into its words. Words are separated by
+// underscores, by a change from a lower case letter or digit to an upper case
+// letter, and before the last letter of a run of upper case letters followed by
+// a lower case letter, so "HTTPServer" is split into "HTTP" and "Server".
+func SplitWords(name string) []string {
+	runes := []rune(name)
+
+	words := []string{}
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+		start = end
+	}
+
+	for i, r := range runes {
+		if r == '_' {
+			flush(i)
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+			flush(i)
+			continue
+		}
+		if unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralSuffix(runes, i+1) {
+			flush(i)
+		}
+	}
+	flush(len(runes))
+
+	return words
+}
+
+// isPluralSuffix reports whether runes[i] is an "s" ending a word, as in "IDs".
+func isPluralSuffix(runes []rune, i int) bool {
+	if runes[i] != 's' {
+		return false
+	}
+	return i+1 == len(runes) || runes[i+1] == '_' || unicode.IsUpper(runes[i+1])
+}
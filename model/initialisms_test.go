@@ -0,0 +1,32 @@
+package model
+
+import "testing"
+
+func TestNamer(t *testing.T) {
+	n := NewNamer(append(CommonInitialisms, "OAuth"))
+
+	tests := []struct {
+		name       string
+		exported   string
+		unexported string
+	}{
+		{"uuid", "UUID", "uuid"},
+		{"userId", "UserID", "userID"},
+		{"httpURL", "HTTPURL", "httpURL"},
+		{"HTTPServer", "HTTPServer", "httpServer"},
+		{"userIDs", "UserIDs", "userIDs"},
+		{"isOrgSuperuser", "IsOrgSuperuser", "isOrgSuperuser"},
+		{"created_at", "CreatedAt", "createdAt"},
+		{"utf8Reader", "UTF8Reader", "utf8Reader"},
+		{"oauthToken", "OAuthToken", "oauthToken"},
+		{"For", "For", "for"},
+	}
+	for _, test := range tests {
+		if got := n.Exported(test.name); got != test.exported {
+			t.Errorf("Exported(%q) = %q, want %q", test.name, got, test.exported)
+		}
+		if got := n.Unexported(test.name); got != test.unexported {
+			t.Errorf("Unexported(%q) = %q, want %q", test.name, got, test.unexported)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package model
 
 import (
-	"reflect"
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
 )
 
 type Model struct {
@@ -11,44 +15,372 @@ type Model struct {
 
 	StructType        *StructType
 	EffectivePackages map[string]string
+
+	// Imports of the generated file sorted by path.
+	Imports []*Import
+
+	// ReceiverName is the name of the struct value in the generated methods and
+	// option closures.
+	ReceiverName string
+
+	// Fields to generate options for.
+	Fields []*Field
+
+	// Diagnostics about the fields no option is generated for.
+	Diagnostics []string
 }
 
 func NewModel(options *Options, p *Package, st *StructType) *Model {
+	return NewFile(p, []*Options{options}, []*StructType{st}).Models[0]
+}
+
+// File is a generated file holding the options of one or more struct types of
+// a package.
+type File struct {
+	Package *Package
 
-	// log.Printf("Model Package: %+#v\n", *p)
+	EffectivePackages map[string]string
 
-	imps := st.getImports()
+	// Imports of the generated file sorted by path.
+	Imports []*Import
 
-	// for _, imp := range imps {
-	// 	log.Printf("%+#v\n", *imp)
-	// }
+	Models []*Model
+}
 
-	// log.Printf("same package field %+#v\n", st.Fields[len(st.Fields)-1].Type.getImports()[0])
+// NewFile returns the file generating the options of each of sts with the
+// options of the same index. The models share the imports of the file.
+func NewFile(p *Package, options []*Options, sts []*StructType) *File {
+	models := []*Model{}
+	for i, st := range sts {
+		fields, diagnostics := selectFields(options[i], st)
+		models = append(models, &Model{
+			Options:     options[i],
+			Package:     p,
+			StructType:  st,
+			Fields:      fields,
+			Diagnostics: diagnostics,
+		})
+	}
+	declareFuncNames(models)
 
-	effectivePackages := CreateEffectivePackages(p, imps)
-	// log.Println("ep", effectivePackages)
+	imps := []*Package{}
+	reserved := []string{p.Name}
+	for _, m := range models {
+		imps = append(imps, getFieldsImports(m.Fields)...)
+		reserved = append(reserved, reservedNames(m.Options, m.StructType, m.Fields)...)
+	}
 
-	return &Model{
-		Options:           options,
+	effectivePackages := CreateEffectivePackages(p, imps, reserved...)
+
+	imports := CreateImports(imps, effectivePackages)
+
+	fileScope := NewScope(NewUniverseScope(), reserved...)
+	for _, imp := range imports {
+		fileScope.Declare(imp.Alias)
+	}
+	for _, m := range models {
+		m.EffectivePackages = effectivePackages
+		m.Imports = imports
+		m.ReceiverName = nameFields(m.Options, m.StructType, m.Fields, fileScope)
+	}
+
+	return &File{
 		Package:           p,
-		StructType:        st,
 		EffectivePackages: effectivePackages,
+		Imports:           imports,
+		Models:            models,
+	}
+}
+
+// File returns the file generating the options of m alone.
+func (m *Model) File() *File {
+	return &File{
+		Package:           m.Package,
+		EffectivePackages: m.EffectivePackages,
+		Imports:           m.Imports,
+		Models:            []*Model{m},
+	}
+}
+
+// FuncField returns the model and field of the option function funcName, or
+// nil if no field of the models of f has it.
+func (f *File) FuncField(funcName string) (*Model, *Field) {
+	for _, m := range f.Models {
+		for _, field := range m.Fields {
+			if field.FuncName == funcName {
+				return m, field
+			}
+		}
+	}
+	return nil, nil
+}
+
+// BuildFlags returns the build flags recorded in the header of the file.
+func (f *File) BuildFlags() []string {
+	if len(f.Models) == 0 {
+		return nil
+	}
+	return f.Models[0].Options.BuildFlags
+}
+
+// BuildConstraint returns the build constraint of the file, which is satisfied
+// when the constraints of all its models are.
+func (f *File) BuildConstraint() string {
+	constraints := []string{}
+	for _, m := range f.Models {
+		constraints = append(constraints, m.Options.BuildConstraint)
 	}
+	return AndConstraints(constraints...)
+}
+
+// License returns the license of the file as // comment lines, or "" if it has
+// none.
+func (f *File) License() string {
+	if len(f.Models) == 0 {
+		return ""
+	}
+	return licenseLines(f.Models[0].Options.License)
+}
+
+// licenseLines returns license, plain text or comments, as // comment lines.
+// /* */ comments are converted, as a //go:build line following them would be
+// ignored.
+func licenseLines(license string) string {
+	license = strings.TrimSpace(license)
+	if !strings.HasPrefix(license, "/*") {
+		if strings.HasPrefix(license, "//") {
+			return license
+		}
+		return commentLines(license)
+	}
+
+	end := strings.Index(license, "*/")
+	if end < 0 {
+		return commentLines(license)
+	}
+	lines := strings.Split(license[len("/*"):end], "\n")
+
+	// The lines of the comment may be started by aligned stars.
+	starred := true
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "*") {
+			starred = false
+		}
+	}
+	if starred {
+		for i, line := range lines[1:] {
+			line = strings.TrimPrefix(strings.TrimLeft(line, " \t"), "*")
+			lines[i+1] = strings.TrimPrefix(line, " ")
+		}
+	}
+
+	result := commentLines(strings.Join(lines, "\n"))
+	if rest := licenseLines(license[end+len("*/"):]); rest != "" {
+		if result != "" {
+			result += "\n"
+		}
+		result += rest
+	}
+	return result
+}
+
+// CommandLine returns the command line recorded in the header of the file.
+func (f *File) CommandLine() string {
+	if len(f.Models) == 0 {
+		return ""
+	}
+	return f.Models[0].Options.CommandLine
+}
+
+// AndConstraints returns the build constraint expression satisfied when all of
+// the non-empty expressions constraints are.
+func AndConstraints(constraints ...string) string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, c := range constraints {
+		if c != "" && !seen[c] {
+			seen[c] = true
+			result = append(result, c)
+		}
+	}
+	if len(result) == 1 {
+		return result[0]
+	}
+	for i, c := range result {
+		result[i] = "(" + c + ")"
+	}
+	return strings.Join(result, " && ")
+}
+
+// declareFuncNames declares the option function names of the fields of models
+// in the scope of their file, next to the struct and option types. Names that
+// spell the same once initialisms are applied, such as those of the fields url
+// and URL, collide, and the later fields are skipped with a diagnostic.
+func declareFuncNames(models []*Model) {
+	fileScope := NewScope(nil)
+	declared := map[string]string{}
+	for _, m := range models {
+		fileScope.Declare(m.StructType.Name)
+		fileScope.Declare(m.Options.OptionName)
+		declared[m.StructType.Name] = "type " + m.StructType.Name
+		declared[m.Options.OptionName] = "type " + m.Options.OptionName
+	}
+
+	for _, m := range models {
+		fields := []*Field{}
+		for _, field := range m.Fields {
+			if fileScope.Lookup(field.FuncName) {
+				m.Diagnostics = append(m.Diagnostics, fmt.Sprintf("skipping field %s: option %s collides with %s", field.Selector, field.FuncName, declared[field.FuncName]))
+				continue
+			}
+			fileScope.Declare(field.FuncName)
+			declared[field.FuncName] = fmt.Sprintf("the option of field %s.%s", m.StructType.Name, field.Selector)
+			fields = append(fields, field)
+		}
+		m.Fields = fields
+	}
+}
+
+// reservedNames returns the file scope identifiers an import must not take
+// in the file generated for st.
+func reservedNames(options *Options, st *StructType, fields []*Field) []string {
+	result := []string{st.Name, options.OptionName}
+	for _, field := range fields {
+		result = append(result, field.FuncName)
+	}
+	return result
 }
 
 // Package path to effect import name.
 // Empty effect name currently means in the same package and not to have prefix
 // before dot (".").
-func CreateEffectivePackages(modelPackage *Package, ps []*Package) map[string]string {
+//
+// Packages are named in order of their paths. A package whose name is already
+// taken by an earlier package or by one of the reserved names is given an
+// alias built from its path, so the result is stable for the same input.
+func CreateEffectivePackages(modelPackage *Package, ps []*Package, reserved ...string) map[string]string {
 	result := map[string]string{}
 
+	names := map[string]string{}
 	for _, p := range ps {
-		if reflect.DeepEqual(modelPackage, p) {
+		if modelPackage.Path == p.Path {
 			result[p.Path] = ""
 			continue
 		}
-		result[p.Path] = p.Name
+		names[p.Path] = p.Name
+	}
+
+	paths := make([]string, 0, len(names))
+	for path := range names {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	used := map[string]bool{}
+	for _, name := range reserved {
+		used[name] = true
+	}
+	for _, path := range paths {
+		alias := importAlias(path, names[path], used)
+		used[alias] = true
+		result[path] = alias
+	}
+
+	return result
+}
+
+// importAlias returns name if it is unused, and otherwise the first unused
+// candidate of prefixing name with elements of path, and then suffixing it with
+// a number.
+func importAlias(path, name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+
+	alias := name
+	elements := strings.Split(path, "/")
+	for i := len(elements) - 2; i >= 0; i-- {
+		element := identifierPart(elements[i])
+		if element == "" || element == alias || isMajorVersion(element) {
+			continue
+		}
+		alias = element + alias
+		if !used[alias] && !token.IsKeyword(alias) {
+			return alias
+		}
 	}
 
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s%d", name, i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// identifierPart returns the lower case letters and digits of s.
+func identifierPart(s string) string {
+	b := &strings.Builder{}
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return strings.TrimLeftFunc(b.String(), unicode.IsDigit)
+}
+
+// isMajorVersion reports whether s is a major version suffix such as "v2".
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Import is a package imported by the generated file.
+type Import struct {
+	Path string
+
+	// Name declared by the package clause of the imported package.
+	Name string
+
+	// Alias the package is referred to by in the generated file. It is equal to
+	// Name unless the import is renamed.
+	Alias string
+}
+
+// IsRenamed reports whether the import needs an explicit name.
+func (i *Import) IsRenamed() bool {
+	return i.Alias != i.Name
+}
+
+// CreateImports returns the imports of ps other than the generated package
+// itself, sorted by path.
+func CreateImports(ps []*Package, effectivePackages map[string]string) []*Import {
+	result := []*Import{}
+
+	seen := map[string]bool{}
+	for _, p := range ps {
+		alias := effectivePackages[p.Path]
+		if alias == "" || seen[p.Path] {
+			continue
+		}
+		seen[p.Path] = true
+		result = append(result, &Import{
+			Path:  p.Path,
+			Name:  p.Name,
+			Alias: alias,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+
 	return result
 }
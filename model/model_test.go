@@ -0,0 +1,101 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCreateEffectivePackages(t *testing.T) {
+	modelPackage := &Package{Path: "example.com/app/template", Name: "template"}
+	ps := []*Package{
+		modelPackage,
+		{Path: "text/template", Name: "template"},
+		{Path: "html/template", Name: "template"},
+		{Path: "errors", Name: "errors"},
+		{Path: "github.com/pkg/errors", Name: "errors"},
+		{Path: "example.com/errors/v2", Name: "errors"},
+		{Path: "example.com/lib/v2", Name: "lib"},
+	}
+
+	got := CreateEffectivePackages(modelPackage, ps, modelPackage.Name, "Option", "WithLib")
+	want := map[string]string{
+		"example.com/app/template": "",
+		"errors":                   "errors",
+		"example.com/errors/v2":    "examplecomerrors",
+		"example.com/lib/v2":       "lib",
+		"github.com/pkg/errors":    "pkgerrors",
+		"html/template":            "htmltemplate",
+		"text/template":            "texttemplate",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateEffectivePackages() = %v, want %v", got, want)
+	}
+}
+
+func TestAndConstraints(t *testing.T) {
+	tests := []struct {
+		constraints []string
+		want        string
+	}{
+		{nil, ""},
+		{[]string{"", ""}, ""},
+		{[]string{"linux", ""}, "linux"},
+		{[]string{"linux", "linux"}, "linux"},
+		{[]string{"linux || darwin", "!appengine"}, "(linux || darwin) && (!appengine)"},
+	}
+	for _, test := range tests {
+		if got := AndConstraints(test.constraints...); got != test.want {
+			t.Errorf("AndConstraints(%q) = %q, want %q", test.constraints, got, test.want)
+		}
+	}
+}
+
+type testInitialismFields struct {
+	URL    string
+	url    string
+	userId int
+	UserID int
+}
+
+func TestNewFileFuncNameCollision(t *testing.T) {
+	st, err := NewStructTypeFromReflectType(reflect.TypeOf(testInitialismFields{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(NewOptions(), &Package{Path: "example.com/app", Name: "app"}, st)
+
+	funcNames := []string{}
+	for _, field := range m.Fields {
+		funcNames = append(funcNames, field.FuncName)
+	}
+	if want := []string{"WithURL", "WithUserID"}; !reflect.DeepEqual(funcNames, want) {
+		t.Errorf("option functions = %v, want %v", funcNames, want)
+	}
+
+	want := []string{
+		"skipping field url: option WithURL collides with the option of field testInitialismFields.URL",
+		"skipping field UserID: option WithUserID collides with the option of field testInitialismFields.userId",
+	}
+	if !reflect.DeepEqual(m.Diagnostics, want) {
+		t.Errorf("diagnostics = %q, want %q", m.Diagnostics, want)
+	}
+}
+
+func TestLicenseLines(t *testing.T) {
+	for _, test := range []struct {
+		license string
+		want    string
+	}{
+		{"", ""},
+		{"Copyright 2026 Shipyard\n\nMIT License\n", "// Copyright 2026 Shipyard\n//\n// MIT License"},
+		{"// Copyright 2026 Shipyard\n", "// Copyright 2026 Shipyard"},
+		{"/* Copyright 2026 Shipyard */", "// Copyright 2026 Shipyard"},
+		{"/*\n * Copyright 2026 Shipyard\n *\n * MIT License\n */\n", "// Copyright 2026 Shipyard\n//\n// MIT License"},
+		{"/*\nCopyright 2026 Shipyard\n\n  Indented\n*/", "// Copyright 2026 Shipyard\n//\n//   Indented"},
+		{"/* Copyright 2026 Shipyard */\n\n// MIT License", "// Copyright 2026 Shipyard\n// MIT License"},
+	} {
+		if got := licenseLines(test.license); got != test.want {
+			t.Errorf("licenseLines(%q) = %q, want %q", test.license, got, test.want)
+		}
+	}
+}
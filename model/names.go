@@ -0,0 +1,89 @@
+package model
+
+import (
+	"fmt"
+)
+
+// predeclaredNames are the identifiers of the universe block.
+var predeclaredNames = []string{
+	// Types.
+	"any", "bool", "byte", "comparable", "complex64", "complex128", "error",
+	"float32", "float64", "int", "int8", "int16", "int32", "int64", "rune",
+	"string", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+
+	// Constants and the zero value.
+	"true", "false", "iota", "nil",
+
+	// Functions.
+	"append", "cap", "clear", "close", "complex", "copy", "delete", "imag",
+	"len", "make", "max", "min", "new", "panic", "print", "println", "real",
+	"recover",
+}
+
+// Scope allocates identifiers for generated code. An identifier declared in a
+// scope does not collide with any identifier declared in the scope or in one of
+// its enclosing scopes.
+type Scope struct {
+	parent *Scope
+	names  map[string]bool
+}
+
+// NewUniverseScope returns a scope holding the predeclared identifiers.
+func NewUniverseScope() *Scope {
+	return NewScope(nil, predeclaredNames...)
+}
+
+// NewScope returns a scope enclosed by parent, which could be nil, with names
+// already declared.
+func NewScope(parent *Scope, names ...string) *Scope {
+	s := &Scope{
+		parent: parent,
+		names:  map[string]bool{},
+	}
+	for _, name := range names {
+		s.names[name] = true
+	}
+	return s
+}
+
+// Lookup reports whether name is declared in s or in an enclosing scope.
+func (s *Scope) Lookup(name string) bool {
+	for ; s != nil; s = s.parent {
+		if s.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Declare declares and returns name, sanitized, or if it is already taken the
+// first free name of adding "Value" and then a number to it.
+func (s *Scope) Declare(name string) string {
+	result := SanitizeName(name)
+	if s.Lookup(result) {
+		base := result + "Value"
+		result = base
+		for i := 2; s.Lookup(result); i++ {
+			result = fmt.Sprintf("%s%d", base, i)
+		}
+	}
+	s.names[result] = true
+	return result
+}
+
+// nameFields allocates the receiver name of st and the argument names of the
+// option functions of fields. fileScope must hold every file scope identifier of
+// the generated file.
+func nameFields(options *Options, st *StructType, fields []*Field, fileScope *Scope) string {
+	// The receiver is declared in the with method next to its locals and in
+	// every option function's closure, so it must not shadow the arguments.
+	receiverScope := NewScope(fileScope, "options", "option")
+	receiverName := receiverScope.Declare(ReceiverName(st.Name))
+
+	for _, field := range fields {
+		argumentScope := NewScope(receiverScope)
+		field.ArgumentName = argumentScope.Declare(options.ArgumentName(field.Name))
+	}
+
+	return receiverName
+}
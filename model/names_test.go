@@ -0,0 +1,32 @@
+package model
+
+import "testing"
+
+func TestScopeDeclare(t *testing.T) {
+	fileScope := NewScope(NewUniverseScope(), "time", "User")
+	receiverScope := NewScope(fileScope)
+	receiverScope.Declare("u")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"u", "uValue"},
+		{"time", "timeValue"},
+		{"len", "lenValue"},
+		{"error", "errorValue"},
+		{"email", "email"},
+		{"for", "for_"},
+		{"byte", "byteValue"},
+	}
+	for _, test := range tests {
+		if got := NewScope(receiverScope).Declare(test.name); got != test.want {
+			t.Errorf("Declare(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	s := NewScope(receiverScope, "uValue")
+	if got := s.Declare("u"); got != "uValue2" {
+		t.Errorf("Declare(%q) = %q, want %q", "u", got, "uValue2")
+	}
+}
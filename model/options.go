@@ -6,29 +6,132 @@ import (
 	"strings"
 )
 
+// TypePlaceholder is replaced by the struct type name in OptionName and
+// OptionPrefix by ForType, so the options of several types of a package do not
+// collide.
+const TypePlaceholder = "{Type}"
+
 type Options struct {
 	OptionName string
 
 	OptionPrefix string
+
+	// Initialisms written in a single case in generated identifiers. Defaults
+	// to CommonInitialisms, and could be extended with project specific ones.
+	Initialisms []string
+
+	// Embedded is how options are generated for embedded fields.
+	Embedded EmbeddedMode
+
+	// CopyLocks generates options for fields whose values contain locks, which
+	// go vet reports as copied by the options, or values of standard types that
+	// must not be copied, such as json.Encoder. Such fields are skipped by
+	// default.
+	CopyLocks bool
+
+	// TestFile writes the options to a _test.go file by default, for types
+	// declared in test files.
+	TestFile bool
+
+	// BuildFlags are the gooptions flags of the go command settings the struct
+	// was loaded with, such as "-tags=integration". They are recorded in the
+	// header of the generated file so it can be regenerated the same way.
+	BuildFlags []string
+
+	// BuildConstraint is the expression of the //go:build line of the
+	// generated file, which should be the one of the file declaring the struct,
+	// possibly restricted further, see AndConstraints.
+	BuildConstraint string
+
+	// License is the text of the license written at the top of the generated
+	// file as // comment lines, given as comments or as plain text.
+	License string
+
+	// CommandLine is the command line generating the file, recorded in its
+	// header if not empty.
+	CommandLine string
+
+	// Templates parsed after GenerateTemplate, in order, which replace its
+	// blocks or the whole file. See ParseTemplates.
+	Templates []*Template
+
+	// Force overwrites destination files that were not generated by
+	// gooptions. It does not change the generated file, so it is left out of
+	// its fingerprint.
+	Force bool `json:"-"`
 }
 
 func NewOptions() *Options {
 	return &Options{
 		OptionName:   "Option",
 		OptionPrefix: "With",
+		Initialisms:  append([]string{}, CommonInitialisms...),
+		Embedded:     EmbeddedValue,
 	}
 }
 
-func (o *Options) OutputFile(typeName, sourceDir, destinationPath string) (string, error) {
-	// TODO detect already exists.
+// ForType returns a copy of o with TypePlaceholder replaced in OptionName and
+// OptionPrefix. At the start of a name the type name is used as is, so
+// "{Type}Option" keeps the type's exportedness, elsewhere it is spelled as an
+// exported identifier, so "With{Type}" gives "WithUser" for "user".
+func (o *Options) ForType(typeName string) *Options {
+	result := *o
+	result.OptionName = o.expandTypePlaceholder(o.OptionName, typeName)
+	result.OptionPrefix = o.expandTypePlaceholder(o.OptionPrefix, typeName)
+	return &result
+}
 
+func (o *Options) expandTypePlaceholder(s, typeName string) string {
+	if strings.HasPrefix(s, TypePlaceholder) {
+		s = typeName + strings.TrimPrefix(s, TypePlaceholder)
+	}
+	return strings.ReplaceAll(s, TypePlaceholder, o.ExportedName(typeName))
+}
+
+// HasTypePlaceholder reports whether the names of the options generated with o
+// depend on the struct type.
+func (o *Options) HasTypePlaceholder() bool {
+	return strings.Contains(o.OptionName, TypePlaceholder)
+}
+
+// FuncName returns the name of the option function for the field fieldName.
+func (o *Options) FuncName(fieldName string) string {
+	return o.OptionPrefix + o.ExportedName(fieldName)
+}
+
+// ExportedName returns name spelled as an exported identifier using the
+// initialisms of o.
+func (o *Options) ExportedName(name string) string {
+	return NewNamer(o.Initialisms).Exported(name)
+}
+
+// ArgumentName returns name spelled as an unexported identifier using the
+// initialisms of o. The result could still be a keyword or collide with other
+// identifiers, see Scope.Declare.
+func (o *Options) ArgumentName(name string) string {
+	return NewNamer(o.Initialisms).Unexported(name)
+}
+
+// OutputPath returns the path of the file to write the options of typeName to.
+// destinationPath is relative to sourceDir, and defaults to a file named after
+// typeName in sourceDir.
+func (o *Options) OutputPath(typeName, sourceDir, destinationPath string) string {
 	if destinationPath == "" {
-		destinationPath = strings.ToLower(typeName + "_options.go")
+		suffix := "_options.go"
+		if o.TestFile {
+			suffix = "_options_test.go"
+		}
+		destinationPath = strings.ToLower(typeName + suffix)
 	}
 	if !filepath.IsAbs(destinationPath) {
 		destinationPath = filepath.Join(sourceDir, destinationPath)
 	}
+	return destinationPath
+}
 
+// OutputFile returns the OutputPath, creating its directory.
+func (o *Options) OutputFile(typeName, sourceDir, destinationPath string) (string, error) {
+	destinationPath = o.OutputPath(typeName, sourceDir, destinationPath)
 	if err := os.MkdirAll(filepath.Dir(destinationPath), 0777); err != nil {
 		return "", err
 	}
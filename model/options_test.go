@@ -0,0 +1,28 @@
+package model
+
+import "testing"
+
+func TestOptionsForType(t *testing.T) {
+	o := NewOptions()
+	o.OptionName = "{Type}Option"
+	o.OptionPrefix = "With{Type}"
+
+	tests := []struct {
+		typeName string
+		option   string
+		funcName string
+	}{
+		{"User", "UserOption", "WithUserName"},
+		{"userFixture", "userFixtureOption", "WithUserFixtureName"},
+		{"apiKey", "apiKeyOption", "WithAPIKeyName"},
+	}
+	for _, test := range tests {
+		got := o.ForType(test.typeName)
+		if got.OptionName != test.option {
+			t.Errorf("ForType(%q).OptionName = %q, want %q", test.typeName, got.OptionName, test.option)
+		}
+		if funcName := got.FuncName("name"); funcName != test.funcName {
+			t.Errorf("ForType(%q).FuncName(%q) = %q, want %q", test.typeName, "name", funcName, test.funcName)
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package model
 
-import "strings"
+import (
+	"path"
+	"regexp"
+	"strings"
+)
 
 type Package struct {
 	Path string
 	Name string
 }
 
+// NewPackage guesses the package name from the last element of pkgPath. The
+// guess is wrong for paths such as "gopkg.in/yaml.v3" or "example.com/lib/v2",
+// so loaders should replace Name with the name from the package clause.
 func NewPackage(pkgPath string) *Package {
 	liSlash := strings.LastIndex(pkgPath, "/")
 
@@ -15,3 +22,17 @@ func NewPackage(pkgPath string) *Package {
 		Name: string(pkgPath[liSlash+1:]),
 	}
 }
+
+// gopkgVersionSuffix is the major version suffix of gopkg.in import paths.
+var gopkgVersionSuffix = regexp.MustCompile(`\.v[0-9]+$`)
+
+// GuessPackageName returns the name of the package importPath by convention,
+// its last element without a major version, so "gopkg.in/yaml.v3" and
+// "example.com/yaml/v3" give "yaml". The package may declare another name.
+func GuessPackageName(importPath string) string {
+	name := path.Base(importPath)
+	if isMajorVersion(name) {
+		name = path.Base(path.Dir(importPath))
+	}
+	return gopkgVersionSuffix.ReplaceAllString(name, "")
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// PluginPrefix is the prefix of the names of the plugin executables, so the
+// plugin "docs" is the executable "gooptions-gen-docs".
+const PluginPrefix = "gooptions-gen-"
+
+// PluginRequest is the JSON document gooptions writes to the standard input of
+// a plugin, which is run in Dir.
+type PluginRequest struct {
+	Version int
+
+	// Parameter is the value of the -plugin-param flag.
+	Parameter string
+
+	// Dir is the directory the files of the plugin are written to.
+	Dir string
+
+	// Files gooptions would generate, each with the models of its struct
+	// types.
+	Files []*PluginFile
+}
+
+// PluginFile is a file gooptions would generate.
+type PluginFile struct {
+	// Name of the file gooptions would write in Dir, such as
+	// "user_options.go".
+	Name string
+
+	*File
+}
+
+// PluginResponse is the JSON document a plugin writes to its standard output.
+type PluginResponse struct {
+	Version int
+
+	// Error reports that the plugin failed, in which case no files are
+	// written.
+	Error string `json:",omitempty"`
+
+	// Files to write.
+	Files []*PluginOutputFile
+}
+
+// PluginOutputFile is a file written for a plugin.
+type PluginOutputFile struct {
+	// Name of the file relative to Dir of the request, separated by slashes,
+	// which must not refer to a file outside of it.
+	Name string
+
+	Content string
+}
+
+// WritePluginRequest writes the request of a plugin to w, with Version set to
+// SchemaVersion.
+func WritePluginRequest(w io.Writer, req *PluginRequest) error {
+	req.Version = SchemaVersion
+	return json.NewEncoder(w).Encode(req)
+}
+
+// ReadPluginRequest reads the request of a plugin from r, usually its standard
+// input.
+func ReadPluginRequest(r io.Reader) (*PluginRequest, error) {
+	req := &PluginRequest{}
+	if err := json.NewDecoder(r).Decode(req); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(req.Version); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// WritePluginResponse writes the response of a plugin to w, usually its
+// standard output, with Version set to SchemaVersion.
+func WritePluginResponse(w io.Writer, resp *PluginResponse) error {
+	resp.Version = SchemaVersion
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// ReadPluginResponse reads the response of a plugin from r and checks the
+// names of its files.
+func ReadPluginResponse(r io.Reader) (*PluginResponse, error) {
+	resp := &PluginResponse{}
+	if err := json.NewDecoder(r).Decode(resp); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(resp.Version); err != nil {
+		return nil, err
+	}
+	for _, file := range resp.Files {
+		if !isLocalPath(file.Name) {
+			return nil, fmt.Errorf("model: plugin file name %q is not a path in the output directory", file.Name)
+		}
+	}
+	return resp, nil
+}
+
+// isLocalPath reports whether name is a relative path that does not leave its
+// directory.
+func isLocalPath(name string) bool {
+	name = filepath.FromSlash(name)
+	if name == "" || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return false
+	}
+	name = filepath.Clean(name)
+	return name != "." && name != ".." && !strings.HasPrefix(name, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,242 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// SchemaVersion is the version of the JSON documents gooptions exchanges with
+// its reflection programs and plugins. It changes when the documents change in
+// ways their readers cannot handle.
+const SchemaVersion = 1
+
+// typeJSON is the JSON encoding of a Type. Kind is the kind of TypeKind, and
+// the other fields are set as the kind requires:
+//
+//	named        Package and Name
+//	predeclared  Name, such as "int", "error" or "interface{}"
+//	pointer      Elem
+//	slice        Elem
+//	array        Len and Elem
+//	map          Key and Elem
+//	chan         ChanDir, which is "chan", "<-chan" or "chan<-", and Elem
+//	func         In and Out
+type typeJSON struct {
+	Kind    string
+	Name    string           `json:",omitempty"`
+	Package *Package         `json:",omitempty"`
+	Len     int              `json:",omitempty"`
+	ChanDir string           `json:",omitempty"`
+	Key     *typeJSON        `json:",omitempty"`
+	Elem    *typeJSON        `json:",omitempty"`
+	In      []*parameterJSON `json:",omitempty"`
+	Out     []*parameterJSON `json:",omitempty"`
+}
+
+type parameterJSON struct {
+	Type     *typeJSON
+	Variadic bool `json:",omitempty"`
+}
+
+func newTypeJSON(t Type) *typeJSON {
+	switch t := t.(type) {
+	case *NamedType:
+		return &typeJSON{Kind: "named", Package: t.Package, Name: t.NameInPackage}
+	case PredeclaredType:
+		return &typeJSON{Kind: "predeclared", Name: string(t)}
+	case *PointerType:
+		return &typeJSON{Kind: "pointer", Elem: newTypeJSON(t.ElementType)}
+	case *ArraySliceType:
+		if t.Len < 0 {
+			return &typeJSON{Kind: "slice", Elem: newTypeJSON(t.ElementType)}
+		}
+		return &typeJSON{Kind: "array", Len: t.Len, Elem: newTypeJSON(t.ElementType)}
+	case *MapType:
+		return &typeJSON{Kind: "map", Key: newTypeJSON(t.KeyType), Elem: newTypeJSON(t.ValueType)}
+	case *ChanType:
+		return &typeJSON{Kind: "chan", ChanDir: t.ChanDir.String(), Elem: newTypeJSON(t.ElementType)}
+	case *FuncType:
+		return &typeJSON{Kind: "func", In: newParametersJSON(t.In), Out: newParametersJSON(t.Out)}
+	}
+	return nil
+}
+
+func newParametersJSON(ps []*Parameter) []*parameterJSON {
+	result := []*parameterJSON{}
+	for _, p := range ps {
+		result = append(result, &parameterJSON{Type: newTypeJSON(p.Type), Variadic: p.Variadic})
+	}
+	return result
+}
+
+// Type returns the Type encoded by tj, which is nil for a nil tj.
+func (tj *typeJSON) Type() (Type, error) {
+	if tj == nil {
+		return nil, nil
+	}
+
+	switch tj.Kind {
+	case "named":
+		return &NamedType{Package: tj.Package, NameInPackage: tj.Name}, nil
+	case "predeclared":
+		return PredeclaredType(tj.Name), nil
+	}
+
+	var key, elem Type
+	var err error
+	if key, err = tj.Key.Type(); err != nil {
+		return nil, err
+	}
+	if elem, err = tj.Elem.Type(); err != nil {
+		return nil, err
+	}
+
+	switch tj.Kind {
+	case "pointer":
+		return &PointerType{ElementType: elem}, nil
+	case "slice":
+		return &ArraySliceType{Len: -1, ElementType: elem}, nil
+	case "array":
+		return &ArraySliceType{Len: tj.Len, ElementType: elem}, nil
+	case "map":
+		return &MapType{KeyType: key, ValueType: elem}, nil
+	case "chan":
+		chanDir, err := parseChanDir(tj.ChanDir)
+		if err != nil {
+			return nil, err
+		}
+		return &ChanType{ChanDir: chanDir, ElementType: elem}, nil
+	case "func":
+		in, err := parametersFromJSON(tj.In)
+		if err != nil {
+			return nil, err
+		}
+		out, err := parametersFromJSON(tj.Out)
+		if err != nil {
+			return nil, err
+		}
+		return &FuncType{In: in, Out: out}, nil
+	}
+	return nil, fmt.Errorf("model: unknown type kind %q", tj.Kind)
+}
+
+func parametersFromJSON(pjs []*parameterJSON) ([]*Parameter, error) {
+	result := []*Parameter{}
+	for _, pj := range pjs {
+		t, err := pj.Type.Type()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &Parameter{Type: t, Variadic: pj.Variadic})
+	}
+	return result, nil
+}
+
+func parseChanDir(s string) (reflect.ChanDir, error) {
+	for _, dir := range []reflect.ChanDir{reflect.BothDir, reflect.RecvDir, reflect.SendDir} {
+		if dir.String() == s {
+			return dir, nil
+		}
+	}
+	return 0, fmt.Errorf("model: unknown channel direction %q", s)
+}
+
+// structFieldJSON is the JSON encoding of a StructField, whose Type and
+// StoreType are encoded as typeJSON.
+type structFieldJSON struct {
+	*structField
+
+	Type      *typeJSON
+	StoreType *typeJSON `json:",omitempty"`
+}
+
+// structField has the fields of StructField without its methods.
+type structField StructField
+
+func (sf *StructField) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&structFieldJSON{
+		structField: (*structField)(sf),
+		Type:        newTypeJSON(sf.Type),
+		StoreType:   newTypeJSON(sf.StoreType),
+	})
+}
+
+func (sf *StructField) UnmarshalJSON(b []byte) error {
+	sfj := &structFieldJSON{structField: (*structField)(sf)}
+	if err := json.Unmarshal(b, sfj); err != nil {
+		return err
+	}
+
+	var err error
+	if sf.Type, err = sfj.Type.Type(); err != nil {
+		return err
+	}
+	sf.StoreType, err = sfj.StoreType.Type()
+	return err
+}
+
+// fieldJSON is the JSON encoding of a Field, whose StructField is not embedded
+// as its methods would encode the Field.
+type fieldJSON struct {
+	StructField  *StructField
+	Selector     string
+	Allocations  []*Allocation
+	FuncName     string
+	ArgumentName string
+}
+
+func (f *Field) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&fieldJSON{
+		StructField:  f.StructField,
+		Selector:     f.Selector,
+		Allocations:  f.Allocations,
+		FuncName:     f.FuncName,
+		ArgumentName: f.ArgumentName,
+	})
+}
+
+func (f *Field) UnmarshalJSON(b []byte) error {
+	fj := &fieldJSON{}
+	if err := json.Unmarshal(b, fj); err != nil {
+		return err
+	}
+	*f = Field{
+		StructField:  fj.StructField,
+		Selector:     fj.Selector,
+		Allocations:  fj.Allocations,
+		FuncName:     fj.FuncName,
+		ArgumentName: fj.ArgumentName,
+	}
+	return nil
+}
+
+type allocationJSON struct {
+	Selector    string
+	ElementType *typeJSON
+}
+
+func (a *Allocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&allocationJSON{Selector: a.Selector, ElementType: newTypeJSON(a.ElementType)})
+}
+
+func (a *Allocation) UnmarshalJSON(b []byte) error {
+	aj := &allocationJSON{}
+	if err := json.Unmarshal(b, aj); err != nil {
+		return err
+	}
+	elementType, err := aj.ElementType.Type()
+	if err != nil {
+		return err
+	}
+	*a = Allocation{Selector: aj.Selector, ElementType: elementType}
+	return nil
+}
+
+// checkSchemaVersion returns an error if a document of version cannot be read.
+func checkSchemaVersion(version int) error {
+	if version != SchemaVersion {
+		return fmt.Errorf("model: unsupported schema version %d, want %d", version, SchemaVersion)
+	}
+	return nil
+}
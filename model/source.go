@@ -0,0 +1,13 @@
+package model
+
+import "embed"
+
+// Source holds the files of the package, without its tests. gooptions copies
+// them into the workspace of its reflection programs when the target package's
+// workspace does not provide the package. New files must be listed here, which
+// TestSource checks.
+//
+//go:embed doc.go encoding.go field.go fingerprint.go format.go generate.go
+//go:embed generate.gotemplate initialisms.go model.go names.go options.go
+//go:embed package.go plugin.go schema.go source.go template.go types.go
+var Source embed.FS
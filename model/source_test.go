@@ -0,0 +1,33 @@
+package model
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	entries, err := ioutil.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if (strings.HasSuffix(name, ".go") || strings.HasSuffix(name, ".gotemplate")) && !strings.HasSuffix(name, "_test.go") {
+			want = append(want, name)
+		}
+	}
+
+	got, err := fs.Glob(Source, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Source has files %v, want %v", got, want)
+	}
+}
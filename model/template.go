@@ -0,0 +1,282 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultTemplateName is the name of GenerateTemplate in the template set.
+const defaultTemplateName = "generate.gotemplate"
+
+// defaultBlockPrefix prefixes the names of the blocks of GenerateTemplate, so
+// they can be called by the templates replacing them.
+const defaultBlockPrefix = "default."
+
+// Template is a user supplied template, parsed after GenerateTemplate so its
+// named templates replace the blocks of the same name, such as "option", which
+// remain available as "default.option". A template with content outside of its
+// definitions replaces the whole file.
+type Template struct {
+	// Name of the template in error messages, usually the path of its file.
+	Name string
+
+	Text string
+}
+
+// OptionData is the data of the "option" template.
+type OptionData struct {
+	Model *Model
+
+	Field *Field
+}
+
+// OptionData returns the data of the "option" template for field of m.
+func (m *Model) OptionData(field *Field) *OptionData {
+	return &OptionData{Model: m, Field: field}
+}
+
+// TemplateFuncs returns the functions available to the templates generating f,
+// which are documented in the package documentation.
+func TemplateFuncs(f *File) template.FuncMap {
+	options := NewOptions()
+	effectivePackages := map[string]string{}
+	if f != nil {
+		if len(f.Models) > 0 {
+			options = f.Models[0].Options
+		}
+		effectivePackages = f.EffectivePackages
+	}
+
+	return template.FuncMap{
+		"ArgumentName": options.ArgumentName,
+		"ExportedName": options.ExportedName,
+		"ReceiverName": ReceiverName,
+		"Title":        title,
+		"join":         strings.Join,
+		"comment":      commentLines,
+
+		"typeString": func(t Type) string {
+			return t.TypeString(effectivePackages)
+		},
+		"qualifier": func(pkgPath string) string {
+			return effectivePackages[pkgPath]
+		},
+
+		"kind":          TypeKind,
+		"elem":          ElementType,
+		"isNamed":       kindIs("named"),
+		"isPredeclared": kindIs("predeclared"),
+		"isPointer":     kindIs("pointer"),
+		"isSlice":       kindIs("slice"),
+		"isArray":       kindIs("array"),
+		"isMap":         kindIs("map"),
+		"isChan":        kindIs("chan"),
+		"isFunc":        kindIs("func"),
+	}
+}
+
+// TypeKind returns the kind of t: "named", "predeclared", "pointer", "slice",
+// "array", "map", "chan" or "func".
+func TypeKind(t Type) string {
+	switch t := t.(type) {
+	case *NamedType:
+		return "named"
+	case PredeclaredType:
+		return "predeclared"
+	case *PointerType:
+		return "pointer"
+	case *ArraySliceType:
+		if t.Len < 0 {
+			return "slice"
+		}
+		return "array"
+	case *MapType:
+		return "map"
+	case *ChanType:
+		return "chan"
+	case *FuncType:
+		return "func"
+	case *StructField:
+		return TypeKind(t.Type)
+	case *Field:
+		return TypeKind(t.Type)
+	}
+	return ""
+}
+
+// ElementType returns the type of the elements of the pointer, slice, array,
+// map or channel type t, or nil for the other kinds.
+func ElementType(t Type) Type {
+	switch t := t.(type) {
+	case *PointerType:
+		return t.ElementType
+	case *ArraySliceType:
+		return t.ElementType
+	case *MapType:
+		return t.ValueType
+	case *ChanType:
+		return t.ElementType
+	case *StructField:
+		return ElementType(t.Type)
+	case *Field:
+		return ElementType(t.Type)
+	}
+	return nil
+}
+
+func kindIs(kind string) func(Type) bool {
+	return func(t Type) bool {
+		return TypeKind(t) == kind
+	}
+}
+
+// title returns s with its first letter in upper case, as the deprecated
+// strings.Title did for identifiers.
+func title(s string) string {
+	if s == "" {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// commentLines returns text as // comment lines, or "" for blank text.
+func commentLines(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line = strings.TrimRight(line, " \t\r"); line == "" {
+			lines[i] = "//"
+		} else {
+			lines[i] = "// " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseTemplates returns GenerateTemplate with templates parsed after it, and
+// the name of the template to execute, which is the last one with content
+// outside of its definitions.
+func ParseTemplates(f *File, templates []*Template) (*template.Template, string, error) {
+	t, err := template.New(defaultTemplateName).Funcs(TemplateFuncs(f)).Parse(GenerateTemplate)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The default blocks stay available as "default.<name>", so a template
+	// replacing a block can still render the default one.
+	for _, block := range t.Templates() {
+		if block.Name() == defaultTemplateName {
+			continue
+		}
+		if _, err := t.AddParseTree(defaultBlockPrefix+block.Name(), block.Tree.Copy()); err != nil {
+			return nil, "", err
+		}
+	}
+
+	root := defaultTemplateName
+	for _, tmpl := range templates {
+		nt, err := t.New(tmpl.Name).Parse(tmpl.Text)
+		if err != nil {
+			return nil, "", err
+		}
+		if nt.Tree != nil && !parse.IsEmptyTree(nt.Tree.Root) {
+			root = tmpl.Name
+		}
+	}
+
+	if err := checkTemplates(t, root); err != nil {
+		return nil, "", err
+	}
+	return t, root, nil
+}
+
+// ValidateTemplates returns an error locating the first problem of templates,
+// such as a syntax error or a call of an undefined template.
+func ValidateTemplates(templates []*Template) error {
+	_, _, err := ParseTemplates(nil, templates)
+	return err
+}
+
+// checkTemplates returns an error if a template reachable from root calls an
+// undefined template, or if a user supplied template defines a template that
+// is never called, which is likely a misspelled block name.
+func checkTemplates(t *template.Template, root string) error {
+	called := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		tmpl := t.Lookup(name)
+		if tmpl == nil || tmpl.Tree == nil {
+			continue
+		}
+		var err error
+		walkTemplateNodes(tmpl.Tree.Root, func(node *parse.TemplateNode) {
+			if err != nil {
+				return
+			}
+			if callee := t.Lookup(node.Name); callee == nil || callee.Tree == nil {
+				location, _ := tmpl.Tree.ErrorContext(node)
+				err = fmt.Errorf("template: %s: no such template %q", location, node.Name)
+				return
+			}
+			if !called[node.Name] {
+				called[node.Name] = true
+				queue = append(queue, node.Name)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	templates := t.Templates()
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name() < templates[j].Name()
+	})
+	for _, tmpl := range templates {
+		// Only the definitions of the user supplied templates are checked, not
+		// the templates of their files.
+		if tmpl.Tree == nil || tmpl.Tree.ParseName == defaultTemplateName || tmpl.Name() == tmpl.Tree.ParseName || called[tmpl.Name()] {
+			continue
+		}
+		location, _ := tmpl.Tree.ErrorContext(tmpl.Tree.Root)
+		return fmt.Errorf("template: %s: template %q is defined but never called", location, tmpl.Name())
+	}
+	return nil
+}
+
+// walkTemplateNodes calls fn for the template calls of the nodes under node.
+func walkTemplateNodes(node parse.Node, fn func(*parse.TemplateNode)) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			walkTemplateNodes(n, fn)
+		}
+	case *parse.IfNode:
+		walkTemplateNodes(node.List, fn)
+		walkTemplateNodes(node.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateNodes(node.List, fn)
+		walkTemplateNodes(node.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateNodes(node.List, fn)
+		walkTemplateNodes(node.ElseList, fn)
+	case *parse.TemplateNode:
+		fn(node)
+	}
+}
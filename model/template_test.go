@@ -0,0 +1,77 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantRoot string
+		wantErr  string
+	}{
+		{
+			name:     "option.gotemplate",
+			text:     `{{ define "option" }}{{ template "default.option" . }}{{ end }}`,
+			wantRoot: defaultTemplateName,
+		},
+		{
+			name:     "file.gotemplate",
+			text:     `{{ template "header" . }}package {{ .Package.Name }}`,
+			wantRoot: "file.gotemplate",
+		},
+		{
+			name:    "typo.gotemplate",
+			text:    "\n{{ define \"optoin\" }}{{ end }}",
+			wantErr: `typo.gotemplate:2:21: template "optoin" is defined but never called`,
+		},
+		{
+			name:    "undefined.gotemplate",
+			text:    "{{ define \"type\" }}\n{{ template \"missing\" . }}{{ end }}",
+			wantErr: `undefined.gotemplate:2:12: no such template "missing"`,
+		},
+		{
+			name:    "syntax.gotemplate",
+			text:    "{{ define \"type\" }}\n{{ if }}{{ end }}",
+			wantErr: "syntax.gotemplate:2:",
+		},
+	}
+	for _, test := range tests {
+		_, root, err := ParseTemplates(nil, []*Template{{Name: test.name, Text: test.text}})
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("ParseTemplates(%s) error = %v, want %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTemplates(%s) error = %v", test.name, err)
+			continue
+		}
+		if root != test.wantRoot {
+			t.Errorf("ParseTemplates(%s) root = %q, want %q", test.name, root, test.wantRoot)
+		}
+	}
+}
+
+func TestTemplateFuncsNames(t *testing.T) {
+	p := &Package{Name: "example", Path: "example.com/example"}
+	sku := NewOptions()
+	sku.Initialisms = append(sku.Initialisms, "SKU")
+	f := NewFile(p, []*Options{NewOptions(), sku}, []*StructType{{Name: "User"}, {Name: "Item"}})
+
+	text := `{{ ExportedName "sku" }}{{ range .Models }} {{ .Options.ExportedName "sku" }}{{ end }} {{ Title "sku" }}`
+	tmpl, root, err := ParseTemplates(f, []*Template{{Name: "names.gotemplate", Text: text}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &strings.Builder{}
+	if err := tmpl.ExecuteTemplate(b, root, f); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "Sku Sku SKU Sku"; got != want {
+		t.Errorf("executed %q = %q, want %q", text, got, want)
+	}
+}
@@ -2,25 +2,11 @@ package model
 
 import (
 	"bytes"
-	"encoding/gob"
 	"fmt"
 	"io"
 	"reflect"
 )
 
-func init() {
-	gob.Register(&StructType{})
-	gob.Register(&StructField{})
-	gob.Register(&TagOptions{})
-	gob.Register(PredeclaredType(""))
-	gob.Register(&PointerType{})
-	gob.Register(&ArraySliceType{})
-	gob.Register(&ChanType{})
-	gob.Register(&MapType{})
-	gob.Register(&FuncType{})
-	gob.Register(&NamedType{})
-}
-
 type Type interface {
 	TypeString(ep map[string]string) string
 
@@ -40,13 +26,28 @@ type StructType struct {
 }
 
 func (st *StructType) getImports() []*Package {
+	return getStructFieldsImports(st.Fields)
+}
+
+func getStructFieldsImports(fields []*StructField) []*Package {
 	result := []*Package{}
-	for _, field := range st.Fields {
+	for _, field := range fields {
 		result = append(result, field.getImports()...)
+		if field.StoreType != nil {
+			result = append(result, field.StoreType.getImports()...)
+		}
+		result = append(result, getStructFieldsImports(field.Fields)...)
 	}
 	return result
 }
 
+// Packages returns every package referenced by the field types of st,
+// including the types of fields promoted from embedded fields. The same
+// package may be returned more than once.
+func (st *StructType) Packages() []*Package {
+	return st.getImports()
+}
+
 func NewStructTypeFromReflectType(rt reflect.Type) (*StructType, error) {
 	if rt.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("model: %v is not a struct type", rt)
@@ -66,10 +67,23 @@ func NewStructTypeFromReflectType(rt reflect.Type) (*StructType, error) {
 }
 
 func NewStructFieldsFromStructType(rt reflect.Type) ([]*StructField, error) {
+	return newStructFields(rt, rt.PkgPath(), map[reflect.Type]bool{rt: true})
+}
+
+// newStructFields returns the fields of rt that can be set from the package
+// pkgPath. seen holds the struct types embedded on the way to rt, which are not
+// descended into again.
+func newStructFields(rt reflect.Type, pkgPath string, seen map[reflect.Type]bool) ([]*StructField, error) {
 	result := []*StructField{}
 
 	for i := 0; i < rt.NumField(); i++ {
-		sf, err := NewStructFieldFromReflectStructField(rt.Field(i))
+		rsf := rt.Field(i)
+		if rsf.PkgPath != "" && rsf.PkgPath != pkgPath {
+			// Unexported field of a struct embedded from another package.
+			continue
+		}
+
+		sf, err := newStructField(rsf, pkgPath, seen)
 		if err != nil {
 			return nil, err
 		}
@@ -80,16 +94,51 @@ func NewStructFieldsFromStructType(rt reflect.Type) ([]*StructField, error) {
 }
 
 func NewStructFieldFromReflectStructField(sf reflect.StructField) (*StructField, error) {
+	return newStructField(sf, sf.PkgPath, map[reflect.Type]bool{})
+}
+
+func newStructField(sf reflect.StructField, pkgPath string, seen map[reflect.Type]bool) (*StructField, error) {
 	type_, err := NewType(sf.Type)
 	if err != nil {
 		return nil, err
 	}
 
-	return &StructField{
+	result := &StructField{
 		Name:       sf.Name,
 		Type:       type_,
 		TagOptions: &TagOptions{},
-	}, nil
+		Embedded:   sf.Anonymous,
+		CopyLock:   copyLock(sf.Type),
+	}
+
+	if sf.Type.PkgPath() == "sync/atomic" {
+		if m, ok := reflect.PtrTo(sf.Type).MethodByName("Store"); ok && m.Type.NumIn() == 2 {
+			result.StoreType, err = NewType(m.Type.In(1))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if sf.Anonymous {
+		et := sf.Type
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		if et.Kind() == reflect.Struct && !seen[et] {
+			result.PromotesFields = true
+			embeddedSeen := map[reflect.Type]bool{et: true}
+			for t := range seen {
+				embeddedSeen[t] = true
+			}
+			result.Fields, err = newStructFields(et, pkgPath, embeddedSeen)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
 }
 
 type StructField struct {
@@ -98,6 +147,85 @@ type StructField struct {
 	Type
 
 	TagOptions *TagOptions
+
+	// Doc is the text of the field's doc comment, or of its line comment if it
+	// has none, without comment markers. It is only known for the fields
+	// declared by the struct itself, and filled in from its source.
+	Doc string
+
+	// Embedded reports whether the field is an embedded field.
+	Embedded bool
+
+	// PromotesFields reports whether the field is an embedded struct or pointer
+	// to struct type, whose fields are promoted.
+	PromotesFields bool
+
+	// Fields promoted from an embedded struct or pointer to struct type that
+	// can be set from the package of the outermost struct.
+	Fields []*StructField
+
+	// CopyLock is the lock contained in the field's value, such as
+	// "sync.Mutex" or "sync.Mutex in example.Cache", or a standard type whose
+	// values must not be copied, such as "json.Encoder", or empty if the value
+	// can be copied.
+	CopyLock string
+
+	// StoreType is the argument type of the Store method of sync/atomic types,
+	// which are set through Store instead of by assignment. Nil for other types.
+	StoreType Type
+}
+
+// copyLock returns the value that values of rt contain and that must not be
+// copied, such as "sync.noCopy in sync.WaitGroup", or "" if they can be copied.
+// Like go vet's copylocks check, a type is a lock if its pointer type has Lock
+// and Unlock methods and the type itself has no Lock method, which includes
+// types with a noCopy marker field. Values of the standard types of
+// noCopyTypes must not be copied either.
+func copyLock(rt reflect.Type) string {
+	if rt.Kind() == reflect.Interface {
+		return ""
+	}
+
+	pt := reflect.PtrTo(rt)
+	_, ptrLock := pt.MethodByName("Lock")
+	_, ptrUnlock := pt.MethodByName("Unlock")
+	_, valueLock := rt.MethodByName("Lock")
+	if ptrLock && ptrUnlock && !valueLock {
+		return rt.String()
+	}
+	if noCopyTypes[rt.PkgPath()+"."+rt.Name()] {
+		return rt.String()
+	}
+
+	var lock string
+	switch rt.Kind() {
+	case reflect.Array:
+		lock = copyLock(rt.Elem())
+	case reflect.Struct:
+		for i := 0; i < rt.NumField() && lock == ""; i++ {
+			lock = copyLock(rt.Field(i).Type)
+		}
+	}
+	if lock != "" {
+		return fmt.Sprintf("%v in %v", lock, rt)
+	}
+	return ""
+}
+
+// noCopyTypes are the standard types, by package path and name, whose values
+// hold state that a copy shares with the value it is copied from, such as
+// buffered data, and which go vet cannot tell apart.
+var noCopyTypes = map[string]bool{
+	"bufio.Reader":          true,
+	"bufio.Scanner":         true,
+	"bufio.Writer":          true,
+	"bytes.Buffer":          true,
+	"encoding/gob.Decoder":  true,
+	"encoding/gob.Encoder":  true,
+	"encoding/json.Decoder": true,
+	"encoding/json.Encoder": true,
+	"encoding/xml.Decoder":  true,
+	"encoding/xml.Encoder":  true,
 }
 
 type TagOptions struct {
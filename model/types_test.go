@@ -0,0 +1,99 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testLocked struct {
+	mu    sync.Mutex
+	Count int
+}
+
+// testRetry has pointer methods and unexported state only, but can be copied.
+type testRetry struct {
+	attempts int
+	backoff  time.Duration
+}
+
+func (r *testRetry) Next() time.Duration {
+	r.attempts++
+	return r.backoff * time.Duration(r.attempts)
+}
+
+type testCopyLocks struct {
+	Mutex     sync.Mutex
+	WaitGroup sync.WaitGroup
+	Locked    testLocked
+	Locks     [2]sync.Mutex
+	LockPtr   *sync.Mutex
+	Buffer    bytes.Buffer
+	Encoder   json.Encoder
+	Retry     testRetry
+	Int       big.Int
+	Builder   strings.Builder
+	Time      time.Time
+	Locker    sync.Locker
+	Count     atomic.Int64
+	Value     atomic.Value
+	Name      string
+}
+
+func TestCopyLock(t *testing.T) {
+	rt := reflect.TypeOf(testCopyLocks{})
+	for _, test := range []struct {
+		field string
+		want  string
+	}{
+		{"Mutex", "sync.Mutex"},
+		{"WaitGroup", "sync.noCopy in sync.WaitGroup"},
+		{"Locked", "sync.Mutex in model.testLocked"},
+		{"Locks", "sync.Mutex in [2]sync.Mutex"},
+		{"LockPtr", ""},
+		{"Buffer", "bytes.Buffer"},
+		{"Encoder", "json.Encoder"},
+		{"Retry", ""},
+		{"Int", ""},
+		{"Builder", ""},
+		{"Time", ""},
+		{"Locker", ""},
+		{"Name", ""},
+	} {
+		sf, _ := rt.FieldByName(test.field)
+		if got := copyLock(sf.Type); got != test.want {
+			t.Errorf("copyLock(%v) = %q, want %q", sf.Type, got, test.want)
+		}
+	}
+}
+
+func TestStoreType(t *testing.T) {
+	st, err := NewStructTypeFromReflectType(reflect.TypeOf(testCopyLocks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storeTypes := map[string]Type{}
+	for _, sf := range st.Fields {
+		storeTypes[sf.Name] = sf.StoreType
+	}
+	for _, test := range []struct {
+		field string
+		want  Type
+	}{
+		{"Count", PredeclaredType("int64")},
+		{"Value", PredeclaredType("interface{}")},
+		{"Mutex", nil},
+		{"Name", nil},
+	} {
+		if got := storeTypes[test.field]; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("StoreType of %s = %#v, want %#v", test.field, got, test.want)
+		}
+	}
+}
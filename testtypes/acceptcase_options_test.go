@@ -0,0 +1,33 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: 28de589658cb0c0ed57773eade47b281fb5c3c583baf16da388640d52ee02ede 3ab91a20616afca6
+
+package testtypes_test
+
+import "github.com/shipyardapp/gooptions/testtypes"
+
+type Option func(*acceptCase)
+
+func (a *acceptCase) with(options ...Option) *acceptCase {
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+func WithUser(user *testtypes.User) Option {
+	return func(a *acceptCase) {
+		a.User = user
+	}
+}
+
+func WithOrgs(orgs []testtypes.Org) Option {
+	return func(a *acceptCase) {
+		a.Orgs = orgs
+	}
+}
+
+func WithSuccess(success bool) Option {
+	return func(a *acceptCase) {
+		a.Success = success
+	}
+}
@@ -0,0 +1,20 @@
+package testtypes
+
+//go:generate go run ../cli/gooptions
+
+// The options of Account are generated by the go:generate directive above,
+// which generates the options of every type annotated with
+// //gooptions:generate.
+//
+//gooptions:generate option=AccountOption prefix=WithAccount
+type Account struct {
+	Owner  *User
+	Limits Limits
+}
+
+//gooptions:generate option=LimitsOption prefix=SetLimit embedded=promote
+type Limits struct {
+	Base
+	Requests int
+	Storage  int64
+}
@@ -0,0 +1,25 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: d6a93f1e0eb742ea075b7cadb9ab8fc1e313affdacc52f3456d068c51a690487 1a20dc9635d8c791
+
+package testtypes
+
+type AccountOption func(*Account)
+
+func (a *Account) with(options ...AccountOption) *Account {
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+func WithAccountOwner(owner *User) AccountOption {
+	return func(a *Account) {
+		a.Owner = owner
+	}
+}
+
+func WithAccountLimits(limits Limits) AccountOption {
+	return func(a *Account) {
+		a.Limits = limits
+	}
+}
@@ -0,0 +1,19 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: edccc123f58c1936a830b05c98fcd0f1cfdd7b1eae2f24965ade2f69afa45161 2b59e469ecdbc1d2
+
+package testtypes
+
+type fixtureQuotaOption func(*fixtureQuota)
+
+func (f *fixtureQuota) with(options ...fixtureQuotaOption) *fixtureQuota {
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+func withFixtureQuotaQuota(quota Quota) fixtureQuotaOption {
+	return func(f *fixtureQuota) {
+		f.quota = quota
+	}
+}
@@ -0,0 +1,8 @@
+//go:build integration
+
+package testtypes
+
+type IntegrationConfig struct {
+	DSN     string
+	Verbose bool
+}
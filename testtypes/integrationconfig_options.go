@@ -0,0 +1,28 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: 8b7f028e40474488828b758937bc015e583c95417a4136645ccf7e1f72564891 f3a8dacd81be80d6
+// gooptions build flags: -tags=integration
+
+//go:build integration
+
+package testtypes
+
+type IntegrationOption func(*IntegrationConfig)
+
+func (i *IntegrationConfig) with(options ...IntegrationOption) *IntegrationConfig {
+	for _, option := range options {
+		option(i)
+	}
+	return i
+}
+
+func WithIntegrationDSN(dsn string) IntegrationOption {
+	return func(i *IntegrationConfig) {
+		i.DSN = dsn
+	}
+}
+
+func WithIntegrationVerbose(verbose bool) IntegrationOption {
+	return func(i *IntegrationConfig) {
+		i.Verbose = verbose
+	}
+}
@@ -0,0 +1,37 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: e1420c61e1fd34438f4d472d998f50918783b3ec041410ef00ea484d50d49482 5e1fe25fb4436c3e
+
+package testtypes
+
+type LimitsOption func(*Limits)
+
+func (l *Limits) with(options ...LimitsOption) *Limits {
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+func SetLimitID(id string) LimitsOption {
+	return func(l *Limits) {
+		l.Base.ID = id
+	}
+}
+
+func SetLimitVersion(version int) LimitsOption {
+	return func(l *Limits) {
+		l.Base.version = version
+	}
+}
+
+func SetLimitRequests(requests int) LimitsOption {
+	return func(l *Limits) {
+		l.Requests = requests
+	}
+}
+
+func SetLimitStorage(storage int64) LimitsOption {
+	return func(l *Limits) {
+		l.Storage = storage
+	}
+}
@@ -0,0 +1,9 @@
+package testtypes
+
+import "time"
+
+//go:generate go run ../cli/gooptions -option QuotaOption -prefix WithQuota
+type Quota struct {
+	Max    int
+	Window time.Duration
+}
@@ -0,0 +1,27 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: a62ec236e8bee0d72e3d0e7df3bc069a65fc166d0e8378c19eed21a7b2821240 b8333a3cc8c8e82a
+
+package testtypes
+
+import "time"
+
+type QuotaOption func(*Quota)
+
+func (q *Quota) with(options ...QuotaOption) *Quota {
+	for _, option := range options {
+		option(q)
+	}
+	return q
+}
+
+func WithQuotaMax(maxValue int) QuotaOption {
+	return func(q *Quota) {
+		q.Max = maxValue
+	}
+}
+
+func WithQuotaWindow(window time.Duration) QuotaOption {
+	return func(q *Quota) {
+		q.Window = window
+	}
+}
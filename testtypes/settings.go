@@ -0,0 +1,15 @@
+package testtypes
+
+import "time"
+
+type ServerSettings struct {
+	// Addr is the address the server listens on.
+	Addr string
+
+	Timeout time.Duration // Timeout of the requests.
+}
+
+type ClientSettings struct {
+	Addr  string
+	Retry int
+}
@@ -0,0 +1,27 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: 805f3fc7819b636f79bfa9fff2ed17dffe7bf8327ac8d7b06cfbc01434200806 9b44c35942a63762
+
+package main
+
+import "time"
+
+type Option func(*config)
+
+func (c *config) with(options ...Option) *config {
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func WithAddr(addr string) Option {
+	return func(c *config) {
+		c.addr = addr
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
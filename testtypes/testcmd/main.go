@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type config struct {
+	addr    string
+	timeout time.Duration
+}
+
+func main() {
+	c := (&config{}).with(WithAddr(":8080"))
+	fmt.Println(c.addr, c.timeout)
+}
@@ -0,0 +1,48 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: 223ed8302387cc8b7aefd627acd8c6744d1fd408b956ead8b894ba6fae550a5a 040262c921f61618
+
+package testtypes
+
+import "time"
+
+type ServerSettingsOption func(*ServerSettings)
+
+func (s *ServerSettings) with(options ...ServerSettingsOption) *ServerSettings {
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+func WithServerSettingsAddr(addr string) ServerSettingsOption {
+	return func(s *ServerSettings) {
+		s.Addr = addr
+	}
+}
+
+func WithServerSettingsTimeout(timeout time.Duration) ServerSettingsOption {
+	return func(s *ServerSettings) {
+		s.Timeout = timeout
+	}
+}
+
+type ClientSettingsOption func(*ClientSettings)
+
+func (c *ClientSettings) with(options ...ClientSettingsOption) *ClientSettings {
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func WithClientSettingsAddr(addr string) ClientSettingsOption {
+	return func(c *ClientSettings) {
+		c.Addr = addr
+	}
+}
+
+func WithClientSettingsRetry(retry int) ClientSettingsOption {
+	return func(c *ClientSettings) {
+		c.Retry = retry
+	}
+}
@@ -2,10 +2,18 @@ package testtypes
 
 import (
 	"encoding/json"
+	htmltemplate "html/template"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 )
 
 type User struct {
+	Base
+	*Audit
+
 	email string `gooptions:"foobar"`
 
 	firstName string
@@ -46,10 +54,34 @@ type User struct {
 	E json.Encoder
 
 	Orgs map[string]*Org
+
+	Rand *rand.Rand
+
+	Text *template.Template
+	HTML *htmltemplate.Template
+
+	U     int
+	Time  time.Duration
+	Error error
+
+	mu     sync.Mutex
+	count  atomic.Int64
+	loaded atomic.Bool
 }
 
 type Org struct{}
 
+type Base struct {
+	ID      string
+	version int
+}
+
+type Audit struct {
+	CreatedAt time.Time
+	Time      time.Time
+	*Base
+}
+
 func A(int uint8) bool {
 	return int == 0
 }
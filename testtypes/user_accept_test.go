@@ -1,6 +1,16 @@
 package testtypes_test
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/shipyardapp/gooptions/testtypes"
+)
+
+type acceptCase struct {
+	User    *testtypes.User
+	Orgs    []testtypes.Org
+	Success bool
+}
 
 func TestUser_Accept(t *testing.T) {
 
@@ -1,9 +1,12 @@
-// DO NOT EDIT. This file was generated by gooptions.
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: e8905e6de0d108902b859c8133c52c361ca4f5585d1338e73de8797158a9a853 ef9d25fd0b9dd684
 
 package testtypes
 
 import (
-	"encoding/json"
+	"html/template"
+	"math/rand/v2"
+	texttemplate "text/template"
 	"time"
 )
 
@@ -16,6 +19,18 @@ func (u *User) with(options ...Option) *User {
 	return u
 }
 
+func WithBase(base Base) Option {
+	return func(u *User) {
+		u.Base = base
+	}
+}
+
+func WithAudit(audit *Audit) Option {
+	return func(u *User) {
+		u.Audit = audit
+	}
+}
+
 func WithEmail(email string) Option {
 	return func(u *User) {
 		u.email = email
@@ -46,21 +61,21 @@ func WithIsOrgSuperuser(isOrgSuperuser bool) Option {
 	}
 }
 
-func WithFor(for0 uintptr) Option {
+func WithFor(for_ uintptr) Option {
 	return func(u *User) {
-		u.For = for0
+		u.For = for_
 	}
 }
 
-func WithByte(byte1 byte) Option {
+func WithByte(byteValue byte) Option {
 	return func(u *User) {
-		u.Byte = byte1
+		u.Byte = byteValue
 	}
 }
 
-func WithRune(rune2 rune) Option {
+func WithRune(runeValue rune) Option {
 	return func(u *User) {
-		u.Rune = rune2
+		u.Rune = runeValue
 	}
 }
 
@@ -76,7 +91,7 @@ func WithNumbers(numbers []int) Option {
 	}
 }
 
-func WithUuid(uuid [16]byte) Option {
+func WithUUID(uuid [16]byte) Option {
 	return func(u *User) {
 		u.uuid = uuid
 	}
@@ -94,15 +109,15 @@ func WithSend(send chan<- int) Option {
 	}
 }
 
-func WithChan(chan3 chan int) Option {
+func WithChan(chan_ chan int) Option {
 	return func(u *User) {
-		u.Chan = chan3
+		u.Chan = chan_
 	}
 }
 
-func WithMap(map4 map[string]int) Option {
+func WithMap(map_ map[string]int) Option {
 	return func(u *User) {
-		u.Map = map4
+		u.Map = map_
 	}
 }
 
@@ -118,14 +133,56 @@ func WithT(t time.Time) Option {
 	}
 }
 
-func WithE(e json.Encoder) Option {
+func WithOrgs(orgs map[string]*Org) Option {
+	return func(u *User) {
+		u.Orgs = orgs
+	}
+}
+
+func WithRand(randValue *rand.Rand) Option {
 	return func(u *User) {
-		u.E = e
+		u.Rand = randValue
 	}
 }
 
-func WithOrgs(orgs map[string]*Org) Option {
+func WithText(text *texttemplate.Template) Option {
 	return func(u *User) {
-		u.Orgs = orgs
+		u.Text = text
+	}
+}
+
+func WithHTML(html *template.Template) Option {
+	return func(u *User) {
+		u.HTML = html
+	}
+}
+
+func WithU(uValue int) Option {
+	return func(u *User) {
+		u.U = uValue
+	}
+}
+
+func WithTime(timeValue time.Duration) Option {
+	return func(u *User) {
+		u.Time = timeValue
+	}
+}
+
+func WithError(errorValue error) Option {
+	return func(u *User) {
+		u.Error = errorValue
+	}
+}
+
+func WithCount(count int64) Option {
+	return func(u *User) {
+		u.count.Store(count)
+	}
+}
+
+func WithLoaded(loaded bool) Option {
+	return func(u *User) {
+		u.loaded.Store(loaded)
 	}
 }
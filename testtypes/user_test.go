@@ -2,5 +2,17 @@ package testtypes
 
 import "testing"
 
+type userFixture struct {
+	name string
+	user *User
+}
+
 func TestUser(t *testing.T) {
 }
+
+// fixtureQuota is generated by the go:generate directive in its doc comment.
+//
+//go:generate go run ../cli/gooptions -option fixtureQuotaOption -prefix withFixtureQuota
+type fixtureQuota struct {
+	quota Quota
+}
@@ -0,0 +1,25 @@
+// Code generated by gooptions. DO NOT EDIT.
+// gooptions fingerprint: 5a9306c1428f9d5d6823a88f4ce9705f6273adb9e5f5f29a351df7cdd3974656 c111e5c9c76a1306
+
+package testtypes
+
+type fixtureOption func(*userFixture)
+
+func (u *userFixture) with(options ...fixtureOption) *userFixture {
+	for _, option := range options {
+		option(u)
+	}
+	return u
+}
+
+func withFixtureName(name string) fixtureOption {
+	return func(u *userFixture) {
+		u.name = name
+	}
+}
+
+func withFixtureUser(user *User) fixtureOption {
+	return func(u *userFixture) {
+		u.user = user
+	}
+}